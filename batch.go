@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Batch Parsing -----
+
+var errNotPDF = errors.New("not a valid PDF")
+
+// batchResultMsg reports one finished file so the batch table can update
+// row by row instead of waiting for the whole folder.
+type batchResultMsg struct {
+	Index   int
+	PO      string
+	Saved   bool // false in preview mode, where the PO is only shown
+	Elapsed time.Duration
+	Err     error
+
+	// Pending is set instead of saving when the user has to answer first: a
+	// duplicateMsg for a PDF already on file under another path, or a
+	// poConflictMsg for a PO already stored with different values.
+	Pending tea.Msg
+}
+
+// batchSaveMu makes each file's checks and save one step, so two workers
+// parsing copies of the same PDF can't both pass the duplicate check.
+var batchSaveMu sync.Mutex
+
+// findPDFs walks dir for *.pdf files, returned in a stable order.
+func findPDFs(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".pdf") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// parseBatchFile parses one file of a batch, saving its PO unless preview is
+// set. It uses the same backend as single uploads, just without the progress
+// display. Each file gets its own timeout; canceling ctx stops the whole batch.
+func parseBatchFile(ctx context.Context, timeout time.Duration, db *sql.DB, preview bool, opts parserOptions, index int, path string) tea.Cmd {
+	return func() tea.Msg {
+		start := time.Now()
+		if err := checkPDFHeader(path); err != nil {
+			return batchResultMsg{index, "", false, time.Since(start), fmt.Errorf("%w: %v", errNotPDF, err), nil}
+		}
+
+		fileCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		// Batches only need the PO number, so always ask for a single object.
+		opts.JSONL = false
+		result := parseSync(fileCtx, opts, path)
+		if result.Err != nil {
+			return batchResultMsg{index, "", false, time.Since(start), result.Err, nil}
+		}
+
+		po := result.PO.Number()
+		if po == "" {
+			return batchResultMsg{index, "", false, time.Since(start), nil, nil}
+		}
+		if preview {
+			return batchResultMsg{index, po, false, time.Since(start), nil, nil}
+		}
+		// As with a single upload, a copy of a stored PDF or a re-import that
+		// differs from the stored record waits for the user.
+		batchSaveMu.Lock()
+		defer batchSaveMu.Unlock()
+		existingPO, existingPDF, dup, err := findDuplicate(db, path)
+		if err != nil {
+			return batchResultMsg{index, po, false, time.Since(start), err, nil}
+		}
+		if dup {
+			return batchResultMsg{index, po, false, time.Since(start), nil, duplicateMsg{po, path, result.PO.RawText, existingPO, existingPDF}}
+		}
+		changes, err := storedChanges(db, po, path, result.PO)
+		if err != nil {
+			return batchResultMsg{index, po, false, time.Since(start), err, nil}
+		}
+		if len(changes) > 0 {
+			return batchResultMsg{index, po, false, time.Since(start), nil, poConflictMsg{po, path, result.PO, changes}}
+		}
+		err = upsertPO(db, po, path, result.PO)
+		return batchResultMsg{index, po, true, time.Since(start), err, nil}
+	}
+}
+
+func newBatchTable() table.Model {
+	return table.New(
+		table.WithColumns([]table.Column{
+			{Title: "File", Width: 30},
+			{Title: "Status", Width: 10},
+			{Title: "PO", Width: 15},
+			{Title: "Time", Width: 8},
+		}),
+		table.WithHeight(15),
+		table.WithFocused(true),
+	)
+}
+
+// startBatch queues every PDF under dir and starts up to m.workers parses.
+// Each result frees a slot for the next queued file, so the pool is driven
+// entirely through the Bubble Tea message loop.
+func (m model) startBatch(dir string) (tea.Model, tea.Cmd) {
+	files, err := findPDFs(dir)
+	if err != nil {
+		m.end()
+		m.status = fmt.Sprintf("Could not read folder: %v", err)
+		return m, nil
+	}
+	if len(files) == 0 {
+		m.end()
+		m.status = "No PDFs found in " + dir
+		return m, nil
+	}
+
+	rows := make([]table.Row, len(files))
+	for i, f := range files {
+		name, err := filepath.Rel(dir, f)
+		if err != nil {
+			name = filepath.Base(f)
+		}
+		rows[i] = table.Row{name, "queued", "", ""}
+	}
+	m.batchFiles = files
+	m.batchTable.SetRows(rows)
+	m.batchTable.GotoTop()
+	m.showBatch = true
+	m.batchNext, m.batchDone, m.batchFailed, m.batchInFlight = 0, 0, 0, 0
+	m.batchPending = make(map[int]tea.Msg)
+	m.setErrorDetail("")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.batchCtx = ctx
+	m.cancelParse = cancel
+
+	var cmds []tea.Cmd
+	for m.batchInFlight < max(m.workers, 1) && m.batchNext < len(m.batchFiles) {
+		cmds = append(cmds, m.dispatchBatchFile())
+	}
+	m.setBatchStatus()
+	return m, tea.Batch(cmds...)
+}
+
+func (m *model) dispatchBatchFile() tea.Cmd {
+	i := m.batchNext
+	m.batchNext++
+	m.batchInFlight++
+	m.setBatchRow(i, "parsing", "", "")
+	return parseBatchFile(m.batchCtx, m.parseTimeout, m.db, m.preview, m.parser, i, m.batchFiles[i])
+}
+
+func (m *model) setBatchRow(i int, status, po, elapsed string) {
+	rows := m.batchTable.Rows()
+	rows[i] = table.Row{rows[i][0], status, po, elapsed}
+	m.batchTable.SetRows(rows)
+}
+
+func (m *model) setBatchStatus() {
+	m.status = fmt.Sprintf("Parsed %d of %d (%d running)...", m.batchDone, len(m.batchFiles), m.batchInFlight)
+}
+
+func (m model) handleBatchResult(msg batchResultMsg) (tea.Model, tea.Cmd) {
+	m.batchInFlight--
+	m.batchDone++
+	elapsed := msg.Elapsed.Round(100 * time.Millisecond).String()
+	if !errors.Is(msg.Err, errNotPDF) {
+		m.stats.record(msg.Elapsed, msg.Err)
+	}
+	switch {
+	case msg.Err == errParseCanceled:
+		m.setBatchRow(msg.Index, "canceled", "", elapsed)
+	case msg.Err != nil:
+		m.batchFailed++
+		m.setBatchRow(msg.Index, "failed", "", elapsed)
+		m.setErrorDetail(m.batchFiles[msg.Index] + ":\n" + msg.Err.Error())
+	case msg.PO == "":
+		m.setBatchRow(msg.Index, "no PO", "", elapsed)
+	case msg.Pending != nil:
+		m.batchPending[msg.Index] = msg.Pending
+		status := "conflict"
+		if _, ok := msg.Pending.(duplicateMsg); ok {
+			status = "duplicate"
+		}
+		m.setBatchRow(msg.Index, status, msg.PO, elapsed)
+	case !msg.Saved:
+		m.setBatchRow(msg.Index, "preview", msg.PO, elapsed)
+	default:
+		m.setBatchRow(msg.Index, "saved", msg.PO, elapsed)
+	}
+
+	canceled := m.batchCtx.Err() != nil
+	if !canceled && m.batchNext < len(m.batchFiles) {
+		cmd := m.dispatchBatchFile()
+		m.setBatchStatus()
+		return m, cmd
+	}
+	if m.batchInFlight > 0 {
+		m.setBatchStatus()
+		return m, nil
+	}
+	return m.finishBatch(canceled)
+}
+
+func (m model) finishBatch(canceled bool) (tea.Model, tea.Cmd) {
+	m.end()
+	if m.cancelParse != nil {
+		m.cancelParse()
+		m.cancelParse = nil
+	}
+	if canceled {
+		for i := m.batchNext; i < len(m.batchFiles); i++ {
+			m.setBatchRow(i, "skipped", "", "")
+		}
+		m.status = fmt.Sprintf("Batch canceled after %d of %d file(s).", m.batchDone, len(m.batchFiles))
+	} else {
+		m.status = fmt.Sprintf("Batch complete: %d file(s), %d failed.", len(m.batchFiles), m.batchFailed)
+	}
+	if m.batchFailed > 0 {
+		m.status += " Press x for the last error."
+	}
+	if n := len(m.batchPending); n > 0 {
+		m.status += fmt.Sprintf(" %d not saved — select a conflict or duplicate row and press enter to review.", n)
+	}
+	m.batchFiles = nil
+	return m, nil
+}
+
+// reviewBatchRow shows the prompt for the selected batch row, if the batch
+// left it unsaved.
+func (m model) reviewBatchRow() (tea.Model, tea.Cmd) {
+	row := m.batchTable.Cursor()
+	pending, ok := m.batchPending[row]
+	if !ok {
+		m.status = "Nothing to review on this row."
+		return m, nil
+	}
+	m.batchReview, m.batchReviewing = row, true
+	if dup, ok := pending.(duplicateMsg); ok {
+		return m.showDuplicate(dup)
+	}
+	return m.showConflict(pending.(poConflictMsg))
+}
+
+// batchReviewed settles the batch row whose prompt was just answered, if the
+// prompt came from one. status is the row's new status; "" leaves the row
+// unsaved so it can be reviewed again.
+func (m *model) batchReviewed(status string) {
+	if !m.batchReviewing {
+		return
+	}
+	m.batchReviewing = false
+	if status == "" {
+		return
+	}
+	delete(m.batchPending, m.batchReview)
+	row := m.batchTable.Rows()[m.batchReview]
+	m.setBatchRow(m.batchReview, status, row[2], row[3])
+}
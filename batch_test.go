@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestBatchKeepsHeaderDetail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.pdf")
+	if err := os.WriteFile(path, []byte("just text"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	msg := parseBatchFile(context.Background(), time.Minute, nil, true, parserOptions{}, 0, path)().(batchResultMsg)
+	if !errors.Is(msg.Err, errNotPDF) {
+		t.Fatalf("err = %v, want errNotPDF", msg.Err)
+	}
+	if !strings.Contains(msg.Err.Error(), `First bytes: "just tex"`) {
+		t.Errorf("err = %v, want the bytes found", msg.Err)
+	}
+}
+
+// runBatch runs a batch over dir to the end, feeding each file's result back
+// into the model.
+func runBatch(t *testing.T, m model, dir string) model {
+	t.Helper()
+	next, cmd := m.startBatch(dir)
+	m = next.(model)
+	queue := []tea.Cmd{cmd}
+	for len(queue) > 0 {
+		cmd, queue = queue[0], queue[1:]
+		if cmd == nil {
+			continue
+		}
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			queue = append(queue, msg...)
+		case batchResultMsg:
+			m, cmd = send(t, m, msg)
+			queue = append(queue, cmd)
+		}
+	}
+	return m
+}
+
+// batchDir is a folder holding one PDF for a batch to parse.
+func batchDir(t *testing.T) (dir, pdf string) {
+	t.Helper()
+	dir = t.TempDir()
+	pdf = filepath.Join(dir, "po1.pdf")
+	if err := os.WriteFile(pdf, []byte("%PDF-1.4\nnew copy\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, pdf
+}
+
+func TestBatchLeavesConflictsUnsaved(t *testing.T) {
+	m := testModel(t)
+	m.db = testDB(t)
+	dir, pdf := batchDir(t)
+	if err := upsertPO(m.db, "PO-1", pdf, order(t, `{"po_number": "PO-1", "vendor": "Acme"}`)); err != nil {
+		t.Fatal(err)
+	}
+	m.parser = execParser(t, `echo '{"po_number": "PO-1", "vendor": "Acme Ltd"}'`)
+
+	m = runBatch(t, m, dir)
+	if got := m.batchTable.Rows()[0][1]; got != "conflict" {
+		t.Fatalf("row status = %q, want conflict", got)
+	}
+	if changes, _ := storedChanges(m.db, "PO-1", pdf, order(t, `{"po_number": "PO-1", "vendor": "Acme"}`)); len(changes) != 0 {
+		t.Fatalf("batch overwrote the stored record: %v", changes)
+	}
+
+	m, _ = press(t, m, "enter")
+	if m.confirmOverwrite == nil {
+		t.Fatal("enter on the conflict row didn't show the diff")
+	}
+	m, cmd := press(t, m, "t")
+	if got := m.batchTable.Rows()[0][1]; got != "taken" {
+		t.Errorf("row status = %q after t, want taken", got)
+	}
+	if msg := cmd().(saveResultMsg); msg.Err != nil {
+		t.Fatal(msg.Err)
+	}
+	if changes, _ := storedChanges(m.db, "PO-1", pdf, order(t, `{"po_number": "PO-1", "vendor": "Acme Ltd"}`)); len(changes) != 0 {
+		t.Errorf("taking the new result didn't save it: %v", changes)
+	}
+}
+
+func TestBatchFlagsCopiesOfOnePDF(t *testing.T) {
+	m := testModel(t)
+	m.db = testDB(t)
+	m.workers = 2
+	dir, pdf := batchDir(t)
+	data, _ := os.ReadFile(pdf)
+	if err := os.WriteFile(filepath.Join(dir, "po1-copy.pdf"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.parser = execParser(t, `echo '{"po_number": "PO-1"}'`)
+
+	m = runBatch(t, m, dir)
+	dup := -1
+	statuses := map[string]int{}
+	for i, row := range m.batchTable.Rows() {
+		statuses[row[1]]++
+		if row[1] == "duplicate" {
+			dup = i
+		}
+	}
+	if statuses["saved"] != 1 || statuses["duplicate"] != 1 {
+		t.Fatalf("row statuses %v, want one saved and one duplicate", statuses)
+	}
+
+	m.batchTable.SetCursor(dup)
+	m, _ = press(t, m, "enter")
+	if m.confirmDuplicate == nil {
+		t.Fatal("enter on the duplicate row didn't ask")
+	}
+	m, _ = press(t, m, "s")
+	if got := m.batchTable.Rows()[dup][1]; got != "skipped" {
+		t.Errorf("row status = %q after s, want skipped", got)
+	}
+	if len(m.batchPending) != 0 {
+		t.Errorf("still pending: %v", m.batchPending)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// ----- Parse Cache -----
+
+// parseCacheDir holds one JSON file per cached parse, named by cacheKey.
+func parseCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pdf-parser", "parses"), nil
+}
+
+// cacheKey identifies a parse of path by the file's size and modification
+// time and by the parser that would run, so editing the PDF or the script,
+// switching interpreter or venv, or picking other extraction options, misses
+// the cache. ok is false when the result shouldn't be cached:
+// streamed records, and anything unlocked with a password.
+func cacheKey(opts parserOptions, path string) (key string, ok bool) {
+	if !opts.Cache || opts.JSONL || opts.Password != "" {
+		return "", false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	var scriptTime, templateTime int64
+	if script, err := os.Stat(opts.Script); err == nil {
+		scriptTime = script.ModTime().UnixNano()
+	}
+	if template, err := os.Stat(opts.Template); err == nil {
+		templateTime = template.ModTime().UnixNano()
+	}
+	python, pythonTime := resolvePython(opts.Python)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%s\x00%s\x00%d\x00%s\x00%s\x00%s\x00%d\x00%s\x00%d",
+		path, info.Size(), info.ModTime().UnixNano(), opts.Backend, opts.Script, scriptTime, opts.Command,
+		opts.OCR, opts.Template, templateTime, python, pythonTime)))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// resolvePython is the interpreter a parse would run, as an absolute path,
+// with its modification time, so an upgrade also misses the cache. A venv's
+// python is usually a symlink to the system one, so the link itself is kept:
+// its path is what picks the venv's packages.
+func resolvePython(python string) (string, int64) {
+	if python == "" {
+		return "", 0
+	}
+	path, err := exec.LookPath(python)
+	if err != nil {
+		return python, 0
+	}
+	path = absPath(path)
+	var modTime int64
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime().UnixNano()
+	}
+	return path, modTime
+}
+
+// loadCachedParse returns the stored result for key, if there is one.
+func loadCachedParse(key string) (parseResultMsg, bool) {
+	dir, err := parseCacheDir()
+	if err != nil {
+		return parseResultMsg{}, false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	if err != nil {
+		return parseResultMsg{}, false
+	}
+	var jsonObj map[string]interface{}
+	if err := json.Unmarshal(data, &jsonObj); err != nil {
+		return parseResultMsg{}, false
+	}
+	return parseResultMsg{decodePurchaseOrder(jsonObj), string(data), nil, 0, true}, true
+}
+
+// storeCachedParse saves raw for key. Failures only cost a re-parse later,
+// so they're logged and otherwise ignored.
+func storeCachedParse(key, raw string) {
+	dir, err := parseCacheDir()
+	if err == nil {
+		err = os.MkdirAll(dir, 0o700)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, key+".json"), []byte(raw), 0o600)
+	}
+	if err != nil {
+		debugLog.Printf("parse cache write error: %v", err)
+	}
+}
+
+// clearParseCache deletes every cached parse (flag -clear-cache).
+func clearParseCache() error {
+	dir, err := parseCacheDir()
+	if err != nil {
+		return fmt.Errorf("cache error: %v", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("cache error: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheKeyCoversInterpreter(t *testing.T) {
+	dir := t.TempDir()
+	pdf := filepath.Join(dir, "po.pdf")
+	if err := os.WriteFile(pdf, []byte("%PDF-1.4\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Two venvs whose python links to the same interpreter.
+	target := filepath.Join(dir, "python3.12")
+	if err := os.WriteFile(target, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var pythons []string
+	for _, venv := range []string{"venv-a", "venv-b"} {
+		link := filepath.Join(dir, venv, "bin", "python")
+		os.MkdirAll(filepath.Dir(link), 0o755)
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+		pythons = append(pythons, link)
+	}
+
+	key := func(python string) string {
+		k, ok := cacheKey(parserOptions{Backend: "python", Python: python, Script: "extract.py", Cache: true}, pdf)
+		if !ok {
+			t.Fatal("parse not cacheable")
+		}
+		return k
+	}
+	if key(pythons[0]) != key(pythons[0]) {
+		t.Error("same interpreter, different keys")
+	}
+	if key(pythons[0]) == key(pythons[1]) {
+		t.Error("different venvs share a cache key")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ----- Cell Detail -----
+
+// Tables cut long values at the column width, ending them with "…". The
+// detail pane (w) shows the selected cell in full below the table; while it's
+// open, left/right pick the cell in the selected row.
+
+// maxDetailLines keeps a huge value from pushing the status off screen.
+const maxDetailLines = 6
+
+// detailTable is the table the pane follows on the active tab.
+func (m model) detailTable() *table.Model {
+	switch m.activeTab {
+	case tabSearch:
+		if m.hasCandidates() {
+			return &m.searchTable
+		}
+	case tabList:
+		if !m.listLoading {
+			return &m.listTable
+		}
+	case tabUpload:
+		if m.hasResult() && m.showItems {
+			return &m.itemsTable
+		}
+		if m.hasResult() {
+			return &m.table
+		}
+	}
+	return nil
+}
+
+// moveDetailCell steps the selected cell left or right, staying in the row.
+func (m *model) moveDetailCell(right bool) {
+	t := m.detailTable()
+	col := min(m.detailCol, len(t.Columns())-1)
+	if right {
+		col++
+	} else {
+		col--
+	}
+	m.detailCol = max(min(col, len(t.Columns())-1), 0)
+}
+
+// withDetail appends the detail pane for t to its rendered view.
+func (m model) withDetail(view string, t table.Model) string {
+	row := t.SelectedRow()
+	if !m.showDetail || row == nil {
+		return view
+	}
+	cols := t.Columns()
+	col := min(m.detailCol, len(cols)-1, len(row)-1)
+	value := row[col]
+	label := cols[col].Title + ":"
+	if ansi.StringWidth(value) > cols[col].Width {
+		label = cols[col].Title + " (cut short in the table):"
+	}
+	width := max(m.width-12, 1) // inside the box, with room to center
+	lines := strings.Split(lipgloss.NewStyle().Width(width).Render(value), "\n")
+	if len(lines) > maxDetailLines {
+		lines = append(lines[:maxDetailLines-1], "…")
+	}
+	block := label + "\n" + strings.Join(lines, "\n") + "\n\n←/→ pick cell, w to close."
+	return view + "\n\n" + m.styles.CenterText.Width(m.width).Render(m.styles.Base.Width(lipgloss.Width(block)).Render(block))
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ----- CLI -----
+
+// Exit codes for parse and search. With -json, a failure is also printed to
+// stderr as {"error": "...", "code": N}, and the specific codes below are
+// used; without it, any failure but a usage error exits 1, as it always has.
+const (
+	exitFailed       = 1 // anything not covered below
+	exitUsage        = 2
+	exitFileNotFound = 3
+	exitParseFailed  = 4
+	exitNotInDB      = 5
+	exitDBError      = 6
+)
+
+const exitCodesHelp = `
+exit codes with -json: 1 other failure, 2 usage, 3 file not found,
+4 parse failed, 5 PO not in database, 6 database error`
+
+// cliErrors reports a subcommand's failures as text or, with -json, as JSON.
+type cliErrors struct {
+	json bool
+}
+
+// fail prints err and returns the exit code for it.
+func (c cliErrors) fail(code int, err error) int {
+	if !c.json {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		if code == exitUsage {
+			return exitUsage
+		}
+		return exitFailed
+	}
+	out, _ := json.Marshal(struct {
+		Error string `json:"error"`
+		Code  int    `json:"code"`
+	}{err.Error(), code})
+	fmt.Fprintln(os.Stderr, string(out))
+	return code
+}
+
+// usage reports a wrong argument count: the usage text without -json, an
+// exitUsage error with it.
+func (c cliErrors) usage(fs *flag.FlagSet, err error) int {
+	if !c.json {
+		fs.Usage()
+		return exitUsage
+	}
+	return c.fail(exitUsage, err)
+}
+
+// newCLIErrors looks for -json in args before they're parsed, so a config
+// file or flag error is reported the same way as every later failure. Under
+// -json the flag package's own error and usage output is silenced.
+func newCLIErrors(fs *flag.FlagSet, args []string) cliErrors {
+	var c cliErrors
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "json" {
+			continue
+		}
+		on, err := strconv.ParseBool(value)
+		c.json = !hasValue || (err == nil && on)
+	}
+	if c.json {
+		fs.SetOutput(io.Discard)
+	}
+	return c
+}
+
+// parseArgs loads the config for a subcommand, returning done with its exit
+// code when there's nothing more to do (-h or a config or flag error).
+func (c cliErrors) parseArgs(fs *flag.FlagSet, args []string) (cfg config, code int, done bool) {
+	cfg, err := loadConfig(fs, args)
+	if err == flag.ErrHelp {
+		if c.json {
+			fs.SetOutput(os.Stderr)
+			fs.Usage()
+		}
+		return cfg, 0, true
+	} else if err != nil {
+		return cfg, c.fail(exitUsage, err), true
+	}
+	return cfg, 0, false
+}
+
+// runParseCommand implements "parse [flags] <file.pdf>": it parses one file
+// without the TUI, prints the JSON to stdout and returns the exit code.
+// Nothing is saved to the database.
+func runParseCommand(args []string) int {
+	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
+	fs.Bool("json", false, "report errors as JSON on stderr, with distinct exit codes")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pdf-parser parse [flags] <file.pdf>")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), exitCodesHelp)
+	}
+	errs := newCLIErrors(fs, args)
+	cfg, code, done := errs.parseArgs(fs, args)
+	if done {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return errs.usage(fs, fmt.Errorf("expected one PDF file, got %d arguments", fs.NArg()))
+	}
+	path := absPath(cleanPath(fs.Arg(0)))
+
+	if cfg.logPath != "" {
+		f, err := openLog(cfg.logPath)
+		if err != nil {
+			return errs.fail(exitFailed, err)
+		}
+		defer f.Close()
+	}
+	if cfg.clearCache {
+		if err := clearParseCache(); err != nil {
+			return errs.fail(exitFailed, err)
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return errs.fail(exitFileNotFound, err)
+	}
+	if err := checkPDFHeader(path); err != nil {
+		return errs.fail(exitParseFailed, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.parseTimeout)
+	defer cancel()
+	opts := cfg.parserOptions()
+	result := parseSync(ctx, opts, path)
+	if result.Err != nil {
+		if result.Err == errParseTimeout {
+			return errs.fail(exitParseFailed, fmt.Errorf("%v after %s", result.Err, cfg.parseTimeout))
+		}
+		return errs.fail(exitParseFailed, result.Err)
+	}
+	if !opts.JSONL {
+		for _, problem := range cfg.schema.check(result.Raw) {
+			fmt.Fprintln(os.Stderr, "Warning: suspect result:", problem)
+		}
+	}
+	fmt.Println(result.Raw)
+	return 0
+}
+
+// runSearchCommand implements "search [flags] <po-number>": it prints the
+// PDF path for an exact PO match, or with -json the whole row, and fails
+// when there's no exact match.
+func runSearchCommand(args []string) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the matching row as JSON, and errors as JSON on stderr with distinct exit codes")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pdf-parser search [flags] <po-number>")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), exitCodesHelp)
+	}
+	errs := newCLIErrors(fs, args)
+	cfg, code, done := errs.parseArgs(fs, args)
+	if done {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return errs.usage(fs, fmt.Errorf("expected one PO number, got %d arguments", fs.NArg()))
+	}
+	po := fs.Arg(0)
+
+	// A lookup never creates, initializes or migrates the database, so it's
+	// opened the way -readonly opens it.
+	driver, source := cfg.dataSource()
+	db, err := openDatabase(driver, source, true)
+	if err != nil {
+		return errs.fail(exitDBError, err)
+	}
+	defer db.Close()
+
+	msg := searchDatabase(db, po, fieldPO)().(searchResultMsg)
+	switch {
+	case msg.Err != nil:
+		return errs.fail(exitDBError, msg.Err)
+	case msg.PDF == "" && *asJSON:
+		return errs.fail(exitNotInDB, fmt.Errorf("PO %s not found", po))
+	case msg.PDF == "":
+		fmt.Fprintln(os.Stderr, msg.Result)
+		for _, m := range msg.Matches {
+			fmt.Fprintf(os.Stderr, "  %s\t%s\n", m.PO, m.PDF)
+		}
+		return exitFailed
+	case !*asJSON:
+		fmt.Println(msg.PDF)
+		return 0
+	}
+
+	row, err := poRow(db, po)
+	if err != nil {
+		return errs.fail(exitDBError, err)
+	}
+	out, _ := json.MarshalIndent(row, "", "  ")
+	fmt.Println(string(out))
+	return 0
+}
+
+// runVacuumCommand implements "vacuum [flags]": it backs the database up to
+// a timestamped file, compacts it and reports the size before and after.
+func runVacuumCommand(args []string) int {
+	fs := flag.NewFlagSet("vacuum", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pdf-parser vacuum [flags]")
+		fs.PrintDefaults()
+	}
+	cfg, err := loadConfig(fs, args)
+	if err == flag.ErrHelp {
+		return 0
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if cfg.readonly {
+		fmt.Fprintln(os.Stderr, "Error: vacuum rewrites the database, which -readonly forbids")
+		return 1
+	}
+	driver, source := cfg.dataSource()
+	db, err := openDatabase(driver, source, cfg.readonly)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	defer db.Close()
+
+	res, err := vacuumDatabase(db, cfg.dbPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	fmt.Printf("Backup: %s\n", res.Backup)
+	fmt.Printf("Size:   %s -> %s\n", humanSize(res.Before), humanSize(res.After))
+	return 0
+}
+
+// dispatchSubcommand runs a CLI subcommand named by args[0], reporting false
+// when args doesn't start with one so the TUI should start.
+func dispatchSubcommand(args []string) (code int, ok bool) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return 0, false
+	}
+	switch args[0] {
+	case "parse":
+		return runParseCommand(args[1:]), true
+	case "search":
+		return runSearchCommand(args[1:]), true
+	case "vacuum":
+		return runVacuumCommand(args[1:]), true
+	case "doctor":
+		return runDoctorCommand(args[1:]), true
+	}
+	return 0, false
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cliEnv points the config, cache and database defaults at a temp dir and
+// returns it.
+func cliEnv(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("PDFPARSER_DB", "")
+	return dir
+}
+
+// runCLI runs a subcommand and returns its exit code, stdout and stderr.
+func runCLI(t *testing.T, run func([]string) int, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	capture := func(f **os.File) (done func() string) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := *f
+		*f = w
+		out := make(chan string)
+		go func() {
+			b, _ := io.ReadAll(r)
+			out <- string(b)
+		}()
+		return func() string {
+			w.Close()
+			*f = saved
+			return <-out
+		}
+	}
+	outDone, errDone := capture(&os.Stdout), capture(&os.Stderr)
+	code = run(args)
+	return code, outDone(), errDone()
+}
+
+func TestSearchLeavesDatabaseAlone(t *testing.T) {
+	dir := cliEnv(t)
+	missing := filepath.Join(dir, "missing.db")
+	if code, _, _ := runCLI(t, runSearchCommand, "-db", missing, "PO-1"); code == 0 {
+		t.Error("search on a missing database succeeded")
+	}
+	if _, err := os.Stat(missing); err == nil {
+		t.Error("search created the database")
+	}
+
+	// An old database: only the original columns and no favorites table.
+	old := filepath.Join(dir, "old.db")
+	db, err := sql.Open("sqlite3", old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE purchase_orders (id INTEGER PRIMARY KEY, po_number TEXT UNIQUE NOT NULL, pdf_path TEXT NOT NULL)",
+		"INSERT INTO purchase_orders (po_number, pdf_path) VALUES ('PO-1', '/po1.pdf')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schema := func() string {
+		var b strings.Builder
+		rows, err := db.Query("SELECT sql FROM sqlite_master ORDER BY name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var s sql.NullString
+			rows.Scan(&s)
+			b.WriteString(s.String + "\n")
+		}
+		return b.String()
+	}
+	before := schema()
+	code, stdout, stderr := runCLI(t, runSearchCommand, "-db", old, "PO-1")
+	if code != 0 || strings.TrimSpace(stdout) != "/po1.pdf" {
+		t.Errorf("code %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	if after := schema(); after != before {
+		t.Errorf("search changed the schema:\n%s\nto\n%s", before, after)
+	}
+	db.Close()
+}
+
+func TestJSONErrorCodes(t *testing.T) {
+	dir := cliEnv(t)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	pdf := write("po.pdf", "%PDF-1.4\n")
+	notPDF := write("notes.txt", "just text\n")
+	failing := write("fail.sh", "#!/bin/sh\nexit 1\n")
+	junk := write("junk.db", "this is not a database")
+	empty := filepath.Join(dir, "empty.db")
+	db, err := openDatabase("sqlite3", empty, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	tests := []struct {
+		name string
+		run  func([]string) int
+		args []string
+		code int
+	}{
+		{"bad log path", runParseCommand, []string{"-log", filepath.Join(dir, "no", "such", "dir.log"), pdf}, exitFailed},
+		{"parse without a file", runParseCommand, nil, exitUsage},
+		{"parse with two files", runParseCommand, []string{pdf, pdf}, exitUsage},
+		{"unknown flag", runParseCommand, []string{"-nope", pdf}, exitUsage},
+		{"search without a PO", runSearchCommand, []string{"-db", empty}, exitUsage},
+		{"missing file", runParseCommand, []string{filepath.Join(dir, "missing.pdf")}, exitFileNotFound},
+		{"not a PDF", runParseCommand, []string{notPDF}, exitParseFailed},
+		{"failing parser", runParseCommand, []string{"-parser", "exec", "-parser-cmd", failing, pdf}, exitParseFailed},
+		{"PO not in database", runSearchCommand, []string{"-db", empty, "PO-404"}, exitNotInDB},
+		{"junk database", runSearchCommand, []string{"-db", junk, "PO-1"}, exitDBError},
+		{"missing database", runSearchCommand, []string{"-db", filepath.Join(dir, "missing.db"), "PO-1"}, exitDBError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCLI(t, tt.run, append([]string{"-json"}, tt.args...)...)
+			var got struct {
+				Error string
+				Code  int
+			}
+			if err := json.Unmarshal([]byte(stderr), &got); err != nil {
+				t.Fatalf("stderr isn't one JSON object: %v\n%s", err, stderr)
+			}
+			if code != tt.code || got.Code != tt.code || got.Error == "" {
+				t.Errorf("exit %d, reported %+v; want code %d", code, got, tt.code)
+			}
+		})
+	}
+}
+
+func TestJSONConfigError(t *testing.T) {
+	dir := cliEnv(t)
+	path, err := configFile()
+	if err != nil || !strings.HasPrefix(path, dir) {
+		t.Fatalf("config file %q: %v", path, err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte(`{"no_such_key": 1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"-json", "PO-1"}, {"--json=true", "PO-1"}} {
+		code, _, stderr := runCLI(t, runSearchCommand, args...)
+		if code != exitUsage || !json.Valid([]byte(stderr)) {
+			t.Errorf("%v: exit %d, stderr %q", args, code, stderr)
+		}
+	}
+	if code, _, stderr := runCLI(t, runSearchCommand, "PO-1"); code != exitUsage || json.Valid([]byte(stderr)) {
+		t.Errorf("without -json: exit %d, stderr %q", code, stderr)
+	}
+}
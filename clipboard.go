@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Clipboard -----
+
+// clipboardMsg reports a copy; Notice is the confirmation to show.
+type clipboardMsg struct {
+	Notice string
+	Err    error
+}
+
+func copyToClipboard(text, notice string) tea.Cmd {
+	return func() tea.Msg {
+		if err := clipboard.WriteAll(text); err != nil {
+			return clipboardMsg{"", fmt.Errorf("Clipboard error: %v", err)}
+		}
+		return clipboardMsg{notice, nil}
+	}
+}
+
+// copyResultJSON copies the parsed result the way e exports it, minus the
+// document text; T shows that, and it would swamp a ticket.
+func copyResultJSON(po PurchaseOrder) tea.Cmd {
+	po.RawText = ""
+	output, err := json.MarshalIndent(po, "", "  ")
+	if err != nil {
+		return func() tea.Msg { return clipboardMsg{"", fmt.Errorf("JSON encode error: %v", err)} }
+	}
+	return copyToClipboard(string(output), "Copied all fields as JSON.")
+}
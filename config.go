@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ----- Config -----
+
+const (
+	defaultDBPath       = "warehouse.db"
+	defaultPython       = "python3"
+	defaultScript       = "parse_cli.py"
+	defaultParseTimeout = 30 * time.Second
+	defaultMaxOutputMB  = 64
+)
+
+// config holds startup settings. Flags win over environment variables, which
+// win over the config file, which wins over the built-in defaults.
+type config struct {
+	dbPath       string
+	driver       string // database/sql driver; see dialects
+	dsn          string // connection string for drivers other than sqlite3
+	pythonPath   string
+	scriptPath   string
+	parseTimeout time.Duration
+	theme        string
+	spinner      string
+	logPath      string
+	workers      int
+	jsonl        bool
+	maxOutputMB  int
+	poFormat     *poFormat
+	backend      string
+	parserCmd    string
+	confirmOpen  bool
+	noCache      bool
+	clearCache   bool
+	schema       *resultSchema // nil when checking is off
+	formats      fieldFormats  // nil when formatting is off
+	readonly     bool
+	ocr          string
+	template     string // prompt template for the script; "" for its own
+}
+
+// fileConfig mirrors config.json in the config dir. Every key is optional;
+// timeout uses Go duration syntax such as "45s" or "2m".
+type fileConfig struct {
+	DB      string `json:"db"`
+	Driver  string `json:"driver"`
+	DSN     string `json:"dsn"`
+	Script  string `json:"script"`
+	Python  string `json:"python"`
+	Theme   string `json:"theme"`
+	Spinner string `json:"spinner"`
+	Timeout string `json:"timeout"`
+	Workers int    `json:"workers"`
+	// MaxOutput caps the parser's stdout, in megabytes.
+	MaxOutput int `json:"max_output"`
+	// POPattern is a regular expression PO-number searches must match.
+	POPattern string `json:"po_pattern"`
+	Parser    string `json:"parser"`
+	ParserCmd string `json:"parser_cmd"`
+	// ConfirmOpen shows the PDF and viewer command before launching it.
+	ConfirmOpen bool `json:"confirm_open"`
+	// Schema is a JSON Schema file parse results are checked against, or
+	// "none" to skip the check.
+	Schema string `json:"schema"`
+	// Formats maps field names to a display format such as "currency".
+	Formats fieldFormats `json:"formats"`
+	// ReadOnly turns off saving, deleting, pinning and exporting.
+	ReadOnly bool `json:"readonly"`
+	// OCR is auto, on or off; Template is a prompt file with {raw_text}.
+	OCR      string `json:"ocr"`
+	Template string `json:"template"`
+}
+
+func configFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// loadConfigFile reads config.json. A missing file yields zero values; a file
+// that exists but can't be used is an error so typos don't go unnoticed.
+func loadConfigFile() (fileConfig, error) {
+	var fc fileConfig
+	path, err := configFile()
+	if err != nil {
+		return fc, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fc, nil
+	} else if err != nil {
+		return fc, fmt.Errorf("config error: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fc); err != nil {
+		return fc, fmt.Errorf("config error in %s: %v", path, err)
+	}
+	if fc.Timeout != "" {
+		if d, err := time.ParseDuration(fc.Timeout); err != nil || d <= 0 {
+			return fc, fmt.Errorf("config error in %s: bad timeout %q", path, fc.Timeout)
+		}
+	}
+	return fc, nil
+}
+
+// loadConfig parses args with fs, so subcommands can share the same flags.
+func loadConfig(fs *flag.FlagSet, args []string) (config, error) {
+	fc, err := loadConfigFile()
+	if err != nil {
+		return config{}, err
+	}
+
+	dbFlag := fs.String("db", "", "path to the SQLite database (env PDFPARSER_DB, default "+defaultDBPath+")")
+	driverFlag := fs.String("driver", "", "database driver: sqlite3 (default), postgres or mysql (config file key \"driver\")")
+	dsnFlag := fs.String("dsn", "", "connection string for -driver other than sqlite3 (env PDFPARSER_DSN, config file key \"dsn\")")
+	scriptFlag := fs.String("script", "", "path to the Python parser script (env PDFPARSER_SCRIPT, default "+defaultScript+")")
+	timeoutFlag := fs.Duration("timeout", defaultParseTimeout, "give up on a parse after this long (config file key \"timeout\")")
+	themeFlag := fs.String("theme", "", "color theme: matrix, solarized or mono (default: last used)")
+	spinnerFlag := fs.String("spinner", "", "spinner style: "+spinnerNames()+" (default "+defaultSpinner+"; config file key \"spinner\")")
+	workersFlag := fs.Int("workers", runtime.NumCPU(), "number of PDFs to parse at once in a batch")
+	jsonlFlag := fs.Bool("jsonl", false, "stream one record per line from the parser (records aren't saved to the database)")
+	maxOutputFlag := fs.Int("max-output", defaultMaxOutputMB, "fail a parse whose output exceeds this many megabytes (config file key \"max_output\")")
+	patternFlag := fs.String("po-pattern", "", "regular expression PO numbers must match, e.g. PO-\\d{6} (config file key \"po_pattern\")")
+	backendFlag := fs.String("parser", "", "parser backend: python (run -script) or exec (run -parser-cmd) (config file key \"parser\")")
+	parserCmdFlag := fs.String("parser-cmd", "", "executable for the exec parser backend (config file key \"parser_cmd\")")
+	confirmOpenFlag := fs.Bool("confirm-open", false, "show the path and viewer command and ask before opening a PDF (config file key \"confirm_open\")")
+	schemaFlag := fs.String("schema", "", "JSON Schema file parse results must match, or \"none\" (default: built-in; config file key \"schema\")")
+	formatsFlag := fs.String("formats", "", "display formats by field, e.g. total=currency,date=date, or \"none\" ("+formatNames()+"; default "+defaultFormats+"; config file key \"formats\")")
+	readonlyFlag := fs.Bool("readonly", false, "never write to the database or export files; parse, search and open only (config file key \"readonly\")")
+	ocrFlag := fs.String("ocr", "", "OCR: auto (pages with little text), on or off (config file key \"ocr\")")
+	templateFlag := fs.String("template", "", "prompt template file for the script, using {raw_text} for the document (config file key \"template\")")
+	noCacheFlag := fs.Bool("no-cache", false, "always run the parser, ignoring and not updating cached results")
+	clearCacheFlag := fs.Bool("clear-cache", false, "delete all cached parse results at startup")
+	logFlag := fs.String("log", "", "append a debug log to this file")
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	timeout := *timeoutFlag
+	if !set["timeout"] && fc.Timeout != "" {
+		timeout, _ = time.ParseDuration(fc.Timeout)
+	}
+	if timeout <= 0 {
+		return config{}, fmt.Errorf("-timeout must be more than 0, got %s", timeout)
+	}
+	workers := *workersFlag
+	if !set["workers"] && fc.Workers > 0 {
+		workers = fc.Workers
+	}
+	maxOutput := *maxOutputFlag
+	if !set["max-output"] && fc.MaxOutput > 0 {
+		maxOutput = fc.MaxOutput
+	}
+	if maxOutput <= 0 {
+		return config{}, fmt.Errorf("-max-output must be at least 1 MB")
+	}
+	format, err := newPOFormat(firstNonEmpty(*patternFlag, fc.POPattern))
+	if err != nil {
+		return config{}, err
+	}
+	confirmOpen := *confirmOpenFlag
+	if !set["confirm-open"] {
+		confirmOpen = fc.ConfirmOpen
+	}
+	ocr := strings.ToLower(firstNonEmpty(*ocrFlag, fc.OCR, defaultOCR))
+	if !slices.Contains(ocrModes, ocr) {
+		return config{}, fmt.Errorf("unknown -ocr %q (choose from %s)", ocr, strings.Join(ocrModes, ", "))
+	}
+	template := firstNonEmpty(*templateFlag, fc.Template)
+	if template != "" {
+		template = absPath(template)
+		if _, err := os.Stat(template); err != nil {
+			return config{}, fmt.Errorf("-template: %v", err)
+		}
+	}
+	readonly := *readonlyFlag
+	if !set["readonly"] {
+		readonly = fc.ReadOnly
+	}
+	spinnerName := strings.ToLower(firstNonEmpty(*spinnerFlag, fc.Spinner, defaultSpinner))
+	if _, ok := spinners[spinnerName]; !ok {
+		return config{}, fmt.Errorf("unknown spinner %q (choose from %s)", spinnerName, spinnerNames())
+	}
+	driver := firstNonEmpty(*driverFlag, fc.Driver, "sqlite3")
+	dsn := firstNonEmpty(*dsnFlag, os.Getenv("PDFPARSER_DSN"), fc.DSN)
+	if err := checkDriver(driver); err != nil {
+		return config{}, err
+	}
+	if driver != "sqlite3" && dsn == "" {
+		return config{}, fmt.Errorf("-driver %s needs a -dsn", driver)
+	}
+	schema, err := loadSchema(firstNonEmpty(*schemaFlag, fc.Schema))
+	if err != nil {
+		return config{}, err
+	}
+	formats := fc.Formats
+	if set["formats"] || formats == nil {
+		formats, err = parseFormats(firstNonEmpty(*formatsFlag, defaultFormats))
+	}
+	if err == nil {
+		err = formats.check()
+	}
+	if err != nil {
+		return config{}, err
+	}
+	backend := firstNonEmpty(*backendFlag, fc.Parser, defaultBackend)
+	parserCmd := firstNonEmpty(*parserCmdFlag, fc.ParserCmd)
+	if _, err := newParser(parserOptions{Backend: backend, Command: parserCmd}); err != nil {
+		return config{}, err
+	}
+
+	return config{
+		dbPath:       dbAbsPath(firstNonEmpty(*dbFlag, os.Getenv("PDFPARSER_DB"), fc.DB, defaultDBPath)),
+		driver:       driver,
+		dsn:          dsn,
+		pythonPath:   firstNonEmpty(os.Getenv("PDFPARSER_PYTHON"), fc.Python, defaultPython),
+		scriptPath:   absPath(firstNonEmpty(*scriptFlag, os.Getenv("PDFPARSER_SCRIPT"), fc.Script, defaultScript)),
+		parseTimeout: timeout,
+		// The theme last picked with t beats the file's, which is only a default.
+		theme:   firstNonEmpty(*themeFlag, loadSavedTheme(), fc.Theme),
+		spinner: spinnerName,
+		logPath: *logFlag,
+		workers: workers,
+		jsonl:   *jsonlFlag,
+
+		maxOutputMB: maxOutput,
+		poFormat:    format,
+		backend:     backend,
+		parserCmd:   parserCmd,
+		confirmOpen: confirmOpen,
+		schema:      schema,
+		formats:     formats,
+		readonly:    readonly,
+		ocr:         ocr,
+		template:    template,
+		noCache:     *noCacheFlag,
+		clearCache:  *clearCacheFlag,
+	}, nil
+}
+
+// dataSource is what openDatabase needs: the SQLite file, or the -dsn for
+// other drivers.
+func (c config) dataSource() (driver, source string) {
+	if c.driver == "" || c.driver == "sqlite3" {
+		return "sqlite3", c.dbPath
+	}
+	return c.driver, c.dsn
+}
+
+// databaseLabel names the database for display without exposing a DSN,
+// which may hold a password.
+func (c config) databaseLabel() string {
+	driver, source := c.dataSource()
+	if driver != "sqlite3" {
+		return driver + " (-dsn)"
+	}
+	return source
+}
+
+// parserOptions is how the TUI and subcommands invoke the parser.
+func (c config) parserOptions() parserOptions {
+	return parserOptions{
+		Backend:   c.backend,
+		Command:   c.parserCmd,
+		Python:    c.pythonPath,
+		Script:    c.scriptPath,
+		JSONL:     c.jsonl,
+		MaxOutput: int64(c.maxOutputMB) << 20,
+		Cache:     !c.noCache,
+		OCR:       c.ocr,
+		Template:  c.template,
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// absPath expands a leading ~ to the home directory and resolves path
+// against the working directory at startup so later lookups don't depend on
+// where commands happen to run.
+func absPath(path string) string {
+	path = expandHome(path)
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// expandHome replaces a leading "~" or "~/" with the user's home directory.
+// Other users' homes ("~bob") aren't supported and are left as typed.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// dbAbsPath is absPath for -db, leaving SQLite's special names such as
+// ":memory:" and file: URIs alone.
+func dbAbsPath(path string) string {
+	if strings.HasPrefix(path, ":") || strings.HasPrefix(path, "file:") {
+		return path
+	}
+	return absPath(path)
+}
+
+// configDir is where per-user state such as search history is kept.
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pdf-parser"), nil
+}
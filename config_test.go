@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPathExpansion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"~", home},
+		{"~/po.pdf", filepath.Join(home, "po.pdf")},
+		{"~bob/po.pdf", filepath.Join(cwd, "~bob/po.pdf")},
+		{"docs/po.pdf", filepath.Join(cwd, "docs/po.pdf")},
+		{"./docs/../po.pdf", filepath.Join(cwd, "po.pdf")},
+		{"/tmp/po.pdf", "/tmp/po.pdf"},
+		{`  "/tmp/my po.pdf"  `, "/tmp/my po.pdf"},
+		{`'~/my po.pdf'`, filepath.Join(home, "my po.pdf")},
+		{`/tmp/my\ po.pdf`, "/tmp/my po.pdf"},
+	}
+	for _, tt := range tests {
+		if got := absPath(cleanPath(tt.in)); got != tt.want {
+			t.Errorf("absPath(cleanPath(%q)) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDBAndScriptFlagsExpand(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("PDFPARSER_DB", "")
+	t.Setenv("PDFPARSER_SCRIPT", "")
+	cwd, _ := os.Getwd()
+	tests := []struct {
+		args               []string
+		wantDB, wantScript string
+	}{
+		{[]string{"-db", "~/w.db", "-script", "~/p.py"}, filepath.Join(home, "w.db"), filepath.Join(home, "p.py")},
+		{[]string{"-db", "data/w.db", "-script", "p.py"}, filepath.Join(cwd, "data/w.db"), filepath.Join(cwd, "p.py")},
+		{[]string{"-db", ":memory:"}, ":memory:", ""},
+		{[]string{"-db", "file:w.db?mode=ro"}, "file:w.db?mode=ro", ""},
+	}
+	for _, tt := range tests {
+		cfg, err := loadConfig(flag.NewFlagSet("test", flag.ContinueOnError), tt.args)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if cfg.dbPath != tt.wantDB {
+			t.Errorf("%v: dbPath = %q, want %q", tt.args, cfg.dbPath, tt.wantDB)
+		}
+		if tt.wantScript != "" && cfg.scriptPath != tt.wantScript {
+			t.Errorf("%v: scriptPath = %q, want %q", tt.args, cfg.scriptPath, tt.wantScript)
+		}
+	}
+}
+
+func TestTimeoutMustBePositive(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	for _, args := range [][]string{{"-timeout", "0"}, {"-timeout", "-5s"}} {
+		if _, err := loadConfig(flag.NewFlagSet("test", flag.ContinueOnError), args); err == nil {
+			t.Errorf("%v: no error", args)
+		}
+	}
+	if _, err := loadConfig(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-timeout", "5s"}); err != nil {
+		t.Errorf("-timeout 5s: %v", err)
+	}
+}
+
+func TestSavedTimeoutIsChecked(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	path, err := configFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	for _, saved := range []string{"0s", "-1m", "soon"} {
+		os.WriteFile(path, []byte(`{"timeout": "`+saved+`"}`), 0o644)
+		if _, err := loadConfigFile(); err == nil {
+			t.Errorf("timeout %q loaded without an error", saved)
+		}
+	}
+
+	m := model{parseTimeout: -time.Minute}
+	m.adjustTimeout(true)
+	if m.parseTimeout < minParseTimeout {
+		t.Errorf("timeout %s after +, want at least %s", m.parseTimeout, minParseTimeout)
+	}
+}
@@ -0,0 +1,525 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Database -----
+
+type saveResultMsg struct {
+	PO  string
+	Err error
+
+	// PDF and Order are what was being saved, for R to retry with.
+	PDF   string
+	Order PurchaseOrder
+}
+
+type deleteResultMsg struct {
+	PO         string
+	RemovedPDF bool
+	Err        error
+}
+
+type poRecord struct {
+	PO       string
+	PDF      string
+	Date     string
+	Favorite bool
+}
+
+// loadAllMsg carries one page of stored POs. HasDate is false when the table
+// has no date-like column to show; Total counts every row, for the page math.
+// Favorites is set when only favorites were loaded.
+type loadAllMsg struct {
+	Records   []poRecord
+	HasDate   bool
+	Page      int
+	Total     int
+	Favorites bool
+	Err       error
+}
+
+// listPageSize is how many POs the list tab loads at a time.
+const listPageSize = 15
+
+// pageCount is the number of list pages for total rows, never less than one.
+func pageCount(total int) int {
+	return max((total+listPageSize-1)/listPageSize, 1)
+}
+
+// openDatabase opens the shared connection used for the whole session and
+// pings it so an unreadable file is reported before the UI starts. source is
+// the SQLite file, or for other drivers the -dsn. A read-only database must
+// already exist and is used as it is.
+func openDatabase(driver, source string, readonly bool) (*sql.DB, error) {
+	if err := checkDriver(driver); err != nil {
+		return nil, err
+	}
+	activeDialect = dialects[driver]
+	shown := source
+	size, existing := int64(0), false
+	if driver == "sqlite3" {
+		size, existing = existingDatabase(source)
+		if readonly && existing {
+			source, existing = "file:"+source+"?mode=ro", false
+		} else if readonly && !strings.HasPrefix(source, ":") {
+			return nil, fmt.Errorf("%s doesn't exist, and opening it read-only won't create it", shown)
+		}
+		// _busy_timeout makes the driver run PRAGMA busy_timeout on every pooled
+		// connection, so SQLite itself waits out short write locks.
+		sep := "?"
+		if strings.Contains(source, "?") {
+			sep = "&"
+		}
+		source += sep + "_busy_timeout=" + strconv.Itoa(busyTimeoutMS)
+	} else {
+		shown = driver + " -dsn" // may hold a password
+	}
+	db, err := sql.Open(driver, source)
+	if err != nil {
+		return nil, fmt.Errorf("DB open error: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, databaseError(shown, err)
+	}
+	if existing {
+		if err := checkDatabaseFile(db, shown, size); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	if !readonly {
+		if err := initSchema(db); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	if driver == "sqlite3" {
+		sessionMu.Lock()
+		sessionFile = watchFile(shown, readonly)
+		sessionMu.Unlock()
+	}
+	return db, nil
+}
+
+// busyTimeoutMS is how long SQLite waits on a lock before reporting busy.
+const busyTimeoutMS = 1000
+
+// busyRetries and busyBackoff bound the retries on top of busy_timeout:
+// waits of 100ms, 200ms, 400ms between attempts.
+const (
+	busyRetries = 3
+	busyBackoff = 100 * time.Millisecond
+)
+
+var errDatabaseBusy = errors.New("The database is busy — another program is writing to it. Try again in a moment.")
+
+// isBusy spots SQLITE_BUSY / SQLITE_LOCKED, which the driver reports as
+// "database is locked" or "database table is locked".
+func isBusy(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "database is locked") || strings.Contains(err.Error(), "table is locked"))
+}
+
+// retryBusy runs op again with exponential backoff while it fails with a
+// busy database, and once more after reconnecting if the SQLite file was
+// replaced; any other result is returned at once.
+func retryBusy(db *sql.DB, op func() error) error {
+	reconnectIfReplaced(db)
+	err := op()
+	for attempt := 0; attempt < busyRetries && isBusy(err); attempt++ {
+		wait := busyBackoff << attempt
+		debugLog.Printf("db busy, retrying in %s: %v", wait, err)
+		time.Sleep(wait)
+		err = op()
+	}
+	if isStale(err) && reconnect(db, err.Error()) {
+		err = op()
+	}
+	return err
+}
+
+// purchaseOrdersSchema matches the table the FastAPI app (app.py) creates, so
+// both tools can share one warehouse.db.
+const purchaseOrdersSchema = `CREATE TABLE IF NOT EXISTS purchase_orders (
+	id INTEGER PRIMARY KEY,
+	po_number TEXT UNIQUE NOT NULL,
+	pdf_path TEXT NOT NULL
+)`
+
+// initSchema creates missing tables on a fresh database. It only ever uses
+// IF NOT EXISTS, so existing tables and rows are left untouched.
+func initSchema(db *sql.DB) error {
+	for _, stmt := range activeDialect.schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("DB schema error: %v", err)
+		}
+	}
+	if err := initHashes(db); err != nil {
+		return err
+	}
+	if err := initFields(db); err != nil {
+		return err
+	}
+	return initFullText(db)
+}
+
+// maxCandidates caps how many partial matches a search lists.
+const maxCandidates = 10
+
+type poMatch struct {
+	PO      string
+	PDF     string
+	Snippet string // text-search hit in context; empty for other searches
+}
+
+// searchField restricts which columns a search looks at.
+type searchField int
+
+const (
+	fieldAll searchField = iota
+	fieldPO
+	fieldVendor
+	fieldInvoice
+	fieldText
+)
+
+var searchFieldNames = []string{"all fields", "PO number", "vendor", "invoice number", "document text"}
+
+func (f searchField) String() string { return searchFieldNames[f] }
+
+// maxSearchResults caps how many rows a multi-column search returns.
+const maxSearchResults = 100
+
+// searchDatabase matches the query against the chosen field. Vendor and
+// invoice columns are optional, so on databases without them an all-fields
+// search quietly falls back to PO numbers only. A locked database is retried
+// a few times before giving up.
+func searchDatabase(db *sql.DB, query string, field searchField) tea.Cmd {
+	return func() tea.Msg {
+		debugLog.Printf("db search: %s=%q", field, query)
+		var msg searchResultMsg
+		err := retryBusy(db, func() error {
+			msg = runSearch(db, query, field)
+			return msg.Err
+		})
+		if isBusy(err) {
+			msg.Err = errDatabaseBusy
+		}
+		return msg
+	}
+}
+
+func runSearch(db *sql.DB, query string, field searchField) searchResultMsg {
+	if field == fieldPO {
+		return searchPO(db, query)
+	}
+	if field == fieldText {
+		matches, err := searchText(db, query)
+		if err != nil {
+			return searchResultMsg{Err: err}
+		}
+		if len(matches) == 0 {
+			return searchResultMsg{Result: "No documents contain that text."}
+		}
+		return searchResultMsg{Result: fmt.Sprintf("%d document(s):", len(matches)), Matches: matches}
+	}
+
+	matches, err := searchColumns(db, query, field)
+	if isMissingColumn(err) {
+		if field == fieldAll {
+			return searchPO(db, query)
+		}
+		return searchResultMsg{Err: fmt.Errorf("This database has no %s column.", field)}
+	} else if err != nil {
+		return searchResultMsg{Err: err}
+	}
+
+	switch {
+	case len(matches) == 1 && matches[0].PO == query:
+		return searchResultMsg{Result: fmt.Sprintf("PDF found: %s", matches[0].PDF), PDF: matches[0].PDF}
+	case len(matches) == 0 && field == fieldAll:
+		return searchPO(db, query)
+	case len(matches) == 0:
+		return searchResultMsg{Result: "No matches."}
+	}
+	return searchResultMsg{
+		Result:  fmt.Sprintf("%d match(es):", len(matches)),
+		Matches: matches,
+	}
+}
+
+// searchPO looks for an exact PO first and only falls back to a partial match
+// when there isn't one, so a known PO still jumps straight to its PDF.
+func searchPO(db *sql.DB, po string) searchResultMsg {
+	var pdfPath string
+	err := db.QueryRow(rebind("SELECT pdf_path FROM purchase_orders WHERE po_number = ?"), po).Scan(&pdfPath)
+	if err == nil {
+		return searchResultMsg{Result: fmt.Sprintf("PDF found: %s", pdfPath), PDF: pdfPath}
+	} else if err != sql.ErrNoRows {
+		return searchResultMsg{Err: fmt.Errorf("DB query error: %v", err)}
+	}
+
+	matches, err := findSimilarPOs(db, po)
+	if err != nil {
+		return searchResultMsg{Err: err}
+	}
+	if len(matches) == 0 {
+		return searchResultMsg{Result: "PO not found."}
+	}
+	return searchResultMsg{
+		Result:  fmt.Sprintf("No exact match. %d similar PO(s):", len(matches)),
+		Matches: matches,
+	}
+}
+
+func searchColumns(db *sql.DB, query string, field searchField) ([]poMatch, error) {
+	var where string
+	var args []interface{}
+	switch field {
+	case fieldVendor:
+		where, args = "vendor "+activeDialect.like, []interface{}{likeContains(query)}
+	case fieldInvoice:
+		where, args = "invoice_number = ?", []interface{}{query}
+	default:
+		where = "po_number = ? OR vendor " + activeDialect.like + " OR invoice_number = ?"
+		args = []interface{}{query, likeContains(query), query}
+	}
+	rows, err := db.Query(rebind("SELECT po_number, pdf_path FROM purchase_orders WHERE "+where+" ORDER BY po_number LIMIT ?"),
+		append(args, maxSearchResults)...)
+	if err != nil {
+		return nil, err
+	}
+	return scanMatches(rows)
+}
+
+// likeEscaper escapes LIKE's wildcards, and the escape character itself, so
+// user input only ever matches literally. Queries pair it with
+// activeDialect.like, which names \ as the escape.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likeContains is a LIKE pattern matching any value that contains s.
+func likeContains(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+func isMissingColumn(err error) bool {
+	return err != nil && strings.Contains(err.Error(), activeDialect.missingColumn)
+}
+
+func findSimilarPOs(db *sql.DB, po string) ([]poMatch, error) {
+	rows, err := db.Query(rebind("SELECT po_number, pdf_path FROM purchase_orders WHERE po_number "+activeDialect.like+" ORDER BY po_number LIMIT ?"),
+		likeContains(po), maxCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("DB query error: %v", err)
+	}
+	return scanMatches(rows)
+}
+
+func scanMatches(rows *sql.Rows) ([]poMatch, error) {
+	defer rows.Close()
+	var matches []poMatch
+	for rows.Next() {
+		var m poMatch
+		if err := rows.Scan(&m.PO, &m.PDF); err != nil {
+			return nil, fmt.Errorf("DB scan error: %v", err)
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("DB query error: %v", err)
+	}
+	return matches, nil
+}
+
+// poRow returns every column of the PO's row except the bulky pdf_text.
+func poRow(db *sql.DB, po string) (map[string]interface{}, error) {
+	rows, err := db.Query(rebind("SELECT * FROM purchase_orders WHERE po_number = ?"), po)
+	if err != nil {
+		return nil, fmt.Errorf("DB query error: %v", err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("DB query error: %v", err)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("DB query error: %v", err)
+		}
+		return nil, fmt.Errorf("PO %s not found", po)
+	}
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, fmt.Errorf("DB scan error: %v", err)
+	}
+	row := make(map[string]interface{}, len(cols))
+	for i, c := range cols {
+		if c == "pdf_text" {
+			continue
+		}
+		if b, ok := values[i].([]byte); ok {
+			values[i] = string(b)
+		}
+		row[c] = values[i]
+	}
+	return row, nil
+}
+
+// saveParseResult records the PO against its source PDF, replacing the path if
+// the PO is already on file.
+func saveParseResult(db *sql.DB, po, pdfPath string, order PurchaseOrder) tea.Cmd {
+	return func() tea.Msg {
+		return saveResultMsg{po, upsertPO(db, po, pdfPath, order), pdfPath, order}
+	}
+}
+
+// upsertPO saves the PO with its extracted text for full-text search, the
+// PDF's hash for spotting duplicates and the parsed fields for diffing a
+// re-parse.
+func upsertPO(db *sql.DB, po, pdfPath string, order PurchaseOrder) error {
+	text := order.RawText
+	debugLog.Printf("db save: po=%q pdf=%s text=%d bytes", po, pdfPath, len(text))
+	var hash sql.NullString
+	if h, err := fileHash(pdfPath); err == nil {
+		hash = sql.NullString{String: h, Valid: true}
+	}
+	err := retryBusy(db, func() error {
+		_, err := db.Exec(rebind("INSERT INTO purchase_orders (po_number, pdf_path, pdf_text, pdf_hash, po_fields) VALUES (?, ?, ?, ?, ?) "+activeDialect.upsert),
+			po, pdfPath, text, hash, storedFields(order))
+		return err
+	})
+	if err != nil {
+		debugLog.Printf("db save error: po=%q: %v", po, err)
+		if isBusy(err) {
+			return errDatabaseBusy
+		}
+		return fmt.Errorf("DB save error: %v", err)
+	}
+	return nil
+}
+
+// deletePO removes the PO's row and, only if removePDF is set, its PDF too.
+func deletePO(db *sql.DB, po, pdfPath string, removePDF bool) tea.Cmd {
+	return func() tea.Msg {
+		debugLog.Printf("db delete: po=%q remove_pdf=%t", po, removePDF)
+		var res sql.Result
+		err := retryBusy(db, func() (err error) {
+			res, err = db.Exec(rebind("DELETE FROM purchase_orders WHERE po_number = ?"), po)
+			return err
+		})
+		if err != nil {
+			return deleteResultMsg{po, false, fmt.Errorf("DB delete error: %v", err)}
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return deleteResultMsg{po, false, fmt.Errorf("PO %s is no longer on file.", po)}
+		}
+		// A PO saved again later starts out unpinned.
+		if _, err := db.Exec(rebind("DELETE FROM favorites WHERE po_number = ?"), po); err != nil {
+			debugLog.Printf("db delete favorite error: po=%q: %v", po, err)
+		}
+		if !removePDF {
+			return deleteResultMsg{po, false, nil}
+		}
+		if err := os.Remove(pdfPath); err != nil {
+			return deleteResultMsg{po, false, fmt.Errorf("Deleted PO %s, but couldn't remove the PDF: %v", po, err)}
+		}
+		return deleteResultMsg{po, true, nil}
+	}
+}
+
+// loadPOPage loads one page of POs, or of favorites only, falling back to the
+// last page when page is past the end (e.g. after deleting the only row on it).
+func loadPOPage(db *sql.DB, page int, favoritesOnly bool) tea.Cmd {
+	return func() tea.Msg {
+		debugLog.Printf("db list page %d", page)
+		var msg loadAllMsg
+		err := retryBusy(db, func() error {
+			msg = queryPOPage(db, page, favoritesOnly)
+			return msg.Err
+		})
+		if isBusy(err) {
+			msg.Err = errDatabaseBusy
+		}
+		return msg
+	}
+}
+
+func queryPOPage(db *sql.DB, page int, favoritesOnly bool) loadAllMsg {
+	hasFavorites, err := hasTable(db, "favorites")
+	if err != nil {
+		return loadAllMsg{Err: err}
+	}
+	from, favorite := "purchase_orders LEFT JOIN favorites f USING (po_number)", "f.po_number IS NOT NULL"
+	switch {
+	case !hasFavorites && favoritesOnly:
+		// Nothing can be pinned yet.
+		from, favorite = "purchase_orders WHERE 1 = 0", "1 = 0"
+	case !hasFavorites:
+		from, favorite = "purchase_orders", "1 = 0"
+	case favoritesOnly:
+		from = "purchase_orders JOIN favorites f USING (po_number)"
+	}
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + from).Scan(&total); err != nil {
+		return loadAllMsg{Err: fmt.Errorf("DB query error: %v", err)}
+	}
+	page = min(max(page, 0), pageCount(total)-1)
+
+	dateCol, err := dateColumn(db)
+	if err != nil {
+		return loadAllMsg{Err: err}
+	}
+	dateExpr := "''"
+	if dateCol != "" {
+		dateExpr = fmt.Sprintf("COALESCE(CAST(%s AS %s), '')", activeDialect.quote(dateCol), activeDialect.textType)
+	}
+
+	rows, err := db.Query(rebind("SELECT po_number, pdf_path, "+dateExpr+", "+favorite+" FROM "+from+" ORDER BY po_number LIMIT ? OFFSET ?"),
+		listPageSize, page*listPageSize)
+	if err != nil {
+		return loadAllMsg{Err: fmt.Errorf("DB query error: %v", err)}
+	}
+	defer rows.Close()
+
+	var records []poRecord
+	for rows.Next() {
+		var r poRecord
+		if err := rows.Scan(&r.PO, &r.PDF, &r.Date, &r.Favorite); err != nil {
+			return loadAllMsg{Err: fmt.Errorf("DB scan error: %v", err)}
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return loadAllMsg{Err: fmt.Errorf("DB query error: %v", err)}
+	}
+	return loadAllMsg{Records: records, HasDate: dateCol != "", Page: page, Total: total, Favorites: favoritesOnly}
+}
+
+// dateColumn finds a date-like column on purchase_orders, if the schema has one.
+func dateColumn(db *sql.DB) (string, error) {
+	cols, err := tableColumns(db, "purchase_orders")
+	if err != nil {
+		return "", err
+	}
+	for _, name := range cols {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "date") || strings.HasSuffix(lower, "_at") {
+			return name, nil
+		}
+	}
+	return "", nil
+}
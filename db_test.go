@@ -0,0 +1,96 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testDB is a fresh SQLite database with the app's schema.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := openDatabase("sqlite3", filepath.Join(t.TempDir(), "test.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLikeSearchMatchesLiterally(t *testing.T) {
+	db := testDB(t)
+	for _, po := range []string{"50%OFF", "50XOFF", "A_B", "AXB", "O'BRIEN-1", `C:\X`, "CZX"} {
+		if err := upsertPO(db, po, "/"+po+".pdf", PurchaseOrder{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"%", []string{"50%OFF"}},
+		{"_", []string{"A_B"}},
+		{"'", []string{"O'BRIEN-1"}},
+		{"' OR '1'='1", nil},
+		{`\`, []string{`C:\X`}},
+		{"0X", []string{"50XOFF"}},
+	}
+	for _, tt := range tests {
+		matches, err := findSimilarPOs(db, tt.query)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.query, err)
+		}
+		var got []string
+		for _, m := range matches {
+			got = append(got, m.PO)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q matched %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestLikeContains(t *testing.T) {
+	if got, want := likeContains(`a%b_c\d`), `%a\%b\_c\\d%`; got != want {
+		t.Errorf("likeContains = %q, want %q", got, want)
+	}
+}
+
+func TestReadOnlyOldDatabase(t *testing.T) {
+	// The schema app.py creates: no favorites, pdf_text or FTS table.
+	path := filepath.Join(t.TempDir(), "warehouse.db")
+	setup, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		purchaseOrdersSchema,
+		"INSERT INTO purchase_orders (po_number, pdf_path) VALUES ('PO-1', '/po1.pdf'), ('PO-2', '/po2.pdf')",
+	} {
+		if _, err := setup.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setup.Close()
+	db, err := openDatabase("sqlite3", path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	msg := queryPOPage(db, 0, false)
+	if msg.Err != nil || len(msg.Records) != 2 || msg.Records[0].Favorite {
+		t.Errorf("list: %+v", msg)
+	}
+	if msg := queryPOPage(db, 0, true); msg.Err != nil || len(msg.Records) != 0 || msg.Total != 0 {
+		t.Errorf("favorites: %+v", msg)
+	}
+	if _, err := searchText(db, "steel"); err == nil || strings.Contains(err.Error(), "no such") {
+		t.Errorf("text search: %v", err)
+	}
+	if msg := runSearch(db, "PO-1", fieldAll); msg.Err != nil || msg.PDF != "/po1.pdf" {
+		t.Errorf("search: %+v", msg)
+	}
+}
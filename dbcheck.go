@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ----- Database Checks -----
+
+// existingDatabase reports path's size when it's a SQLite file already on
+// disk. It must run before the driver connects, which creates a missing file.
+func existingDatabase(path string) (size int64, ok bool) {
+	if strings.HasPrefix(path, ":") || strings.HasPrefix(path, "file:") {
+		return 0, false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	return info.Size(), true
+}
+
+// checkDatabaseFile catches an unusable SQLite file before initSchema. A file
+// that didn't exist is simply created as before; one that is empty or lacks
+// purchase_orders is only initialized after confirmation, in case -db points
+// somewhere unexpected. One that isn't SQLite at all can't be fixed here.
+func checkDatabaseFile(db *sql.DB, path string, size int64) error {
+	if size == 0 {
+		return confirmInit(path, "is empty")
+	}
+	var tables int
+	err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'purchase_orders'").Scan(&tables)
+	if err != nil {
+		return databaseError(path, err)
+	}
+	if tables == 0 {
+		return confirmInit(path, "has no purchase_orders table")
+	}
+	return nil
+}
+
+// databaseError explains a failure to read path, singling out a file that
+// isn't a database at all.
+func databaseError(path string, err error) error {
+	if isNotDatabase(err) {
+		return fmt.Errorf("%s is not a SQLite database, or it's encrypted or damaged. Move it aside or pick another file with -db", path)
+	}
+	return fmt.Errorf("cannot open database %s: %v", path, err)
+}
+
+// isNotDatabase spots SQLITE_NOTADB, which the driver reports as "file is not
+// a database" or "file is encrypted or is not a database".
+func isNotDatabase(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "not a database")
+}
+
+// confirmInit asks on the terminal before creating the schema in path. When
+// stdin isn't a terminal there's nobody to ask, so it goes ahead and says so.
+func confirmInit(path, reason string) error {
+	fd := os.Stdin.Fd()
+	if !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "Database %s %s; initializing it.\n", path, reason)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Database %s %s. Initialize a fresh schema in it? [y/N] ", path, reason)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("database %s left as it is; pass -db to use another file", path)
+}
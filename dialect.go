@@ -0,0 +1,175 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ----- SQL Dialects -----
+
+// sqlDialect holds what differs between the supported databases. Queries are
+// written for SQLite and passed through rebind; the rest is looked up here.
+type sqlDialect struct {
+	name string
+	// numbered placeholders ($1, $2, ...) instead of ?.
+	numbered bool
+	// schema creates purchase_orders and favorites.
+	schema []string
+	// columns lists a table's columns: a query taking the table name, or ""
+	// for SQLite's PRAGMA table_info.
+	columns string
+	// upsert finishes the INSERT in upsertPO.
+	upsert string
+	// like is the case-insensitive LIKE and its escape clause.
+	like string
+	// textType is what CAST produces a string with.
+	textType string
+	// quote wraps an identifier.
+	quote func(string) string
+	// missingColumn is part of the error for an unknown column.
+	missingColumn string
+	// fullText and vacuum are SQLite-only features.
+	fullText, vacuum bool
+}
+
+var sqliteDialect = sqlDialect{
+	name:          "sqlite3",
+	schema:        []string{purchaseOrdersSchema, favoritesSchema},
+	upsert:        `ON CONFLICT(po_number) DO UPDATE SET pdf_path = excluded.pdf_path, pdf_text = excluded.pdf_text, pdf_hash = excluded.pdf_hash, po_fields = excluded.po_fields`,
+	like:          `LIKE ? ESCAPE '\'`,
+	textType:      "TEXT",
+	quote:         doubleQuote,
+	missingColumn: "no such column",
+	fullText:      true,
+	vacuum:        true,
+}
+
+var postgresDialect = sqlDialect{
+	name:     "postgres",
+	numbered: true,
+	schema: []string{
+		`CREATE TABLE IF NOT EXISTS purchase_orders (
+	id SERIAL PRIMARY KEY,
+	po_number TEXT UNIQUE NOT NULL,
+	pdf_path TEXT NOT NULL
+)`,
+		favoritesSchema,
+	},
+	columns:       "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ?",
+	upsert:        sqliteDialect.upsert,
+	like:          `ILIKE ? ESCAPE '\'`,
+	textType:      "TEXT",
+	quote:         doubleQuote,
+	missingColumn: "does not exist",
+}
+
+var mysqlDialect = sqlDialect{
+	name: "mysql",
+	schema: []string{
+		`CREATE TABLE IF NOT EXISTS purchase_orders (
+	id INTEGER AUTO_INCREMENT PRIMARY KEY,
+	po_number VARCHAR(255) UNIQUE NOT NULL,
+	pdf_path TEXT NOT NULL
+)`,
+		`CREATE TABLE IF NOT EXISTS favorites (
+	po_number VARCHAR(255) PRIMARY KEY
+)`,
+	},
+	columns:       "SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?",
+	upsert:        `ON DUPLICATE KEY UPDATE pdf_path = VALUES(pdf_path), pdf_text = VALUES(pdf_text), pdf_hash = VALUES(pdf_hash), po_fields = VALUES(po_fields)`,
+	like:          `LIKE ? ESCAPE '\\'`, // MySQL reads backslashes in string literals
+	textType:      "CHAR",
+	quote:         func(s string) string { return "`" + strings.ReplaceAll(s, "`", "``") + "`" },
+	missingColumn: "Unknown column",
+}
+
+// dialects maps -driver names to their dialect.
+var dialects = map[string]sqlDialect{
+	"sqlite3":  sqliteDialect,
+	"postgres": postgresDialect,
+	"mysql":    mysqlDialect,
+}
+
+// activeDialect is the open database's. openDatabase sets it; a process only
+// ever opens one database.
+var activeDialect = sqliteDialect
+
+// checkDriver reports a driver that isn't supported or wasn't built in.
+func checkDriver(driver string) error {
+	if _, ok := dialects[driver]; !ok {
+		return fmt.Errorf("unsupported -driver %q (use sqlite3, postgres or mysql)", driver)
+	}
+	if !slices.Contains(sql.Drivers(), driver) {
+		// The build tag matches the driver name; see driver_*.go.
+		return fmt.Errorf("the %s driver isn't built in; rebuild with -tags %s", driver, driver)
+	}
+	return nil
+}
+
+func doubleQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// rebind rewrites the ? placeholders in query for the active dialect,
+// leaving any inside quoted strings alone.
+func rebind(query string) string {
+	if !activeDialect.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// tableColumns lists table's column names.
+func tableColumns(db *sql.DB, table string) ([]string, error) {
+	var rows *sql.Rows
+	var err error
+	if activeDialect.columns == "" {
+		rows, err = db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	} else {
+		rows, err = db.Query(rebind(activeDialect.columns), table)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("DB query error: %v", err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("DB query error: %v", err)
+	}
+	var names []string
+	for rows.Next() {
+		// PRAGMA table_info returns cid, name, type, ...; the others just name.
+		values := make([]interface{}, len(cols))
+		var name string
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		nameAt := 0
+		if len(cols) > 1 {
+			nameAt = 1
+		}
+		values[nameAt] = &name
+		if err := rows.Scan(values...); err != nil {
+			return nil, fmt.Errorf("DB scan error: %v", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- File Dialog -----
+
+// dialogUnavailableMsg is sent when no native file picker can be found, so
+// the UI can fall back to asking for a path directly.
+type dialogUnavailableMsg struct {
+	dir bool
+}
+
+type dirSelectedMsg string
+
+const windowsPickerScript = `Add-Type -AssemblyName System.Windows.Forms
+$d = New-Object System.Windows.Forms.OpenFileDialog
+$d.Filter = 'PDF files (*.pdf)|*.pdf'
+if ($d.ShowDialog() -eq [System.Windows.Forms.DialogResult]::OK) { $d.FileName }`
+
+const windowsFolderScript = `Add-Type -AssemblyName System.Windows.Forms
+$d = New-Object System.Windows.Forms.FolderBrowserDialog
+if ($d.ShowDialog() -eq [System.Windows.Forms.DialogResult]::OK) { $d.SelectedPath }`
+
+// fileDialogCommand returns the native file picker for the current OS, or nil
+// when the required tool isn't installed.
+func fileDialogCommand() *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("osascript"); err != nil {
+			return nil
+		}
+		return exec.Command("osascript", "-e", `POSIX path of (choose file of type {"com.adobe.pdf"} with prompt "Select a PDF")`)
+	case "windows":
+		if _, err := exec.LookPath("powershell"); err != nil {
+			return nil
+		}
+		return exec.Command("powershell", "-NoProfile", "-STA", "-Command", windowsPickerScript)
+	default:
+		if _, err := exec.LookPath("zenity"); err != nil {
+			return nil
+		}
+		return exec.Command("zenity", "--file-selection", "--file-filter=PDF files (pdf) | *.pdf")
+	}
+}
+
+// dirDialogCommand is the folder-picking counterpart to fileDialogCommand.
+func dirDialogCommand() *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("osascript"); err != nil {
+			return nil
+		}
+		return exec.Command("osascript", "-e", `POSIX path of (choose folder with prompt "Select a folder of PDFs")`)
+	case "windows":
+		if _, err := exec.LookPath("powershell"); err != nil {
+			return nil
+		}
+		return exec.Command("powershell", "-NoProfile", "-STA", "-Command", windowsFolderScript)
+	default:
+		if _, err := exec.LookPath("zenity"); err != nil {
+			return nil
+		}
+		return exec.Command("zenity", "--file-selection", "--directory")
+	}
+}
+
+func openFileDialog() tea.Msg {
+	cmd := fileDialogCommand()
+	if cmd == nil {
+		return dialogUnavailableMsg{}
+	}
+	return fileSelectedMsg(runDialog(cmd))
+}
+
+func openDirDialog() tea.Msg {
+	cmd := dirDialogCommand()
+	if cmd == nil {
+		return dialogUnavailableMsg{dir: true}
+	}
+	return dirSelectedMsg(runDialog(cmd))
+}
+
+// runDialog returns the absolute path picked, or "" if the user canceled.
+func runDialog(cmd *exec.Cmd) string {
+	out, err := cmd.Output()
+	if err != nil {
+		// All supported pickers exit non-zero when the user cancels.
+		return ""
+	}
+	path := strings.TrimSpace(string(out))
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path
+}
+
+// cleanPath undoes what terminals add when a file is dragged in: surrounding
+// whitespace and quotes, or backslash-escaped spaces.
+func cleanPath(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	if filepath.Separator == '/' {
+		s = strings.ReplaceAll(s, `\ `, " ")
+	}
+	return s
+}
+
+// validatePDFPath checks that a manually entered path names an existing .pdf file.
+func validatePDFPath(path string) error {
+	if path == "" {
+		return errors.New("No path entered.")
+	}
+	if !strings.EqualFold(filepath.Ext(path), ".pdf") {
+		return fmt.Errorf("Not a .pdf file: %s", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("File not found: %s", path)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("Path is a directory: %s", path)
+	}
+	return nil
+}
+
+func validateDirPath(path string) error {
+	if path == "" {
+		return errors.New("No path entered.")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("Folder not found: %s", path)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("Not a folder: %s", path)
+	}
+	return nil
+}
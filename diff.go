@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Re-parse Diff -----
+
+// fieldChange is one stored value that a save would change. Mark is "+"
+// for a value the stored row lacks, "-" for one the new parse lacks and "~"
+// for a changed value.
+type fieldChange struct {
+	Mark  string
+	Field string
+	Old   string
+	New   string
+}
+
+// poConflictMsg means the PO is already on file with different values; the
+// save waits for the user to pick a side.
+type poConflictMsg struct {
+	PO      string
+	PDF     string
+	Order   PurchaseOrder
+	Changes []fieldChange
+}
+
+// saveOrDiff saves the PO unless the same PDF is already on file under
+// another path, or the PO is stored with different values; then it returns
+// the duplicate or the differences instead of overwriting anything.
+func saveOrDiff(db *sql.DB, po, pdfPath string, order PurchaseOrder) tea.Cmd {
+	return func() tea.Msg {
+		existingPO, existingPDF, dup, err := findDuplicate(db, pdfPath)
+		if err != nil {
+			return saveResultMsg{po, err, pdfPath, order}
+		}
+		if dup {
+			return duplicateMsg{po, pdfPath, order.RawText, existingPO, existingPDF}
+		}
+		changes, err := storedChanges(db, po, pdfPath, order)
+		if err != nil {
+			return saveResultMsg{po, err, pdfPath, order}
+		}
+		if len(changes) > 0 {
+			return poConflictMsg{po, pdfPath, order, changes}
+		}
+		return saveResultMsg{po, upsertPO(db, po, pdfPath, order), pdfPath, order}
+	}
+}
+
+// initFields adds po_fields, the parsed fields as last saved, to older
+// databases.
+func initFields(db *sql.DB) error {
+	has, err := hasColumn(db, "purchase_orders", "po_fields")
+	if err != nil || has {
+		return err
+	}
+	if _, err := db.Exec("ALTER TABLE purchase_orders ADD COLUMN po_fields TEXT"); err != nil {
+		return fmt.Errorf("DB schema error: %v", err)
+	}
+	return nil
+}
+
+// storedFields is the po_fields value for order: its fields, except the raw
+// text, flattened to the keys the fields table shows, as a JSON object.
+func storedFields(order PurchaseOrder) string {
+	parsed := order.Map()
+	delete(parsed, "_raw_text")
+	values := make(map[string]string)
+	for _, f := range flattenFields(parsed) {
+		values[f.Key] = csvValue(f.Value)
+	}
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+// storedChanges compares what upsertPO writes against the stored row, field
+// by field. A PO that isn't on file yet has no changes, and a row saved
+// before po_fields existed is only compared on its path and text.
+func storedChanges(db *sql.DB, po, pdfPath string, order PurchaseOrder) ([]fieldChange, error) {
+	var oldPDF string
+	var oldText, oldFields sql.NullString
+	err := retryBusy(db, func() error {
+		return db.QueryRow(rebind("SELECT pdf_path, pdf_text, po_fields FROM purchase_orders WHERE po_number = ?"), po).Scan(&oldPDF, &oldText, &oldFields)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		if isBusy(err) {
+			return nil, errDatabaseBusy
+		}
+		return nil, fmt.Errorf("DB query error: %v", err)
+	}
+	var changes []fieldChange
+	if change, ok := compareField("PDF path", oldPDF, pdfPath, oldPDF, pdfPath); ok {
+		changes = append(changes, change)
+	}
+	if oldFields.Valid {
+		changes = append(changes, fieldChanges(oldFields.String, storedFields(order))...)
+	}
+	// Document text is too long to show; compare it whole but display sizes.
+	text := order.RawText
+	if change, ok := compareField("document text", oldText.String, text, textSize(oldText.String), textSize(text)); ok {
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// fieldChanges diffs two po_fields values key by key. Stored fields that
+// can't be read are treated as unknown, not as all removed.
+func fieldChanges(stored, parsed string) []fieldChange {
+	var before, after map[string]string
+	if json.Unmarshal([]byte(stored), &before) != nil || json.Unmarshal([]byte(parsed), &after) != nil {
+		return nil
+	}
+	keys := make(map[string]interface{}, len(before)+len(after))
+	for k := range before {
+		keys[k] = nil
+	}
+	for k := range after {
+		keys[k] = nil
+	}
+	var changes []fieldChange
+	for _, k := range orderedKeys(keys) {
+		o, inOld := before[k]
+		n, inNew := after[k]
+		switch {
+		case !inOld:
+			changes = append(changes, fieldChange{"+", k, "", n})
+		case !inNew:
+			changes = append(changes, fieldChange{"-", k, o, ""})
+		case o != n:
+			changes = append(changes, fieldChange{"~", k, o, n})
+		}
+	}
+	return changes
+}
+
+// compareField reports a change from stored to parsed, displayed as
+// storedShown and parsedShown.
+func compareField(field, stored, parsed, storedShown, parsedShown string) (fieldChange, bool) {
+	switch {
+	case stored == parsed:
+		return fieldChange{}, false
+	case stored == "":
+		return fieldChange{"+", field, "", parsedShown}, true
+	case parsed == "":
+		return fieldChange{"-", field, storedShown, ""}, true
+	}
+	return fieldChange{"~", field, storedShown, parsedShown}, true
+}
+
+func textSize(text string) string {
+	if text == "" {
+		return ""
+	}
+	return fmt.Sprintf("%d characters", len([]rune(text)))
+}
+
+// diffColumns splits width between the stored and new values.
+func diffColumns(width int) []table.Column {
+	const markWidth, fieldWidth = 1, 20
+	valueWidth := 30
+	if width > 0 {
+		valueWidth = max((width-markWidth-fieldWidth-4*cellPadding)/2, minColumnWidth)
+	}
+	return []table.Column{
+		{Title: "", Width: markWidth},
+		{Title: "Field", Width: fieldWidth},
+		{Title: "Stored", Width: valueWidth},
+		{Title: "New", Width: valueWidth},
+	}
+}
+
+func diffRows(changes []fieldChange) []table.Row {
+	rows := make([]table.Row, len(changes))
+	for i, c := range changes {
+		rows[i] = table.Row{c.Mark, c.Field, c.Old, c.New}
+	}
+	return rows
+}
+
+// showConflict puts the differences on screen and waits for a choice.
+func (m model) showConflict(msg poConflictMsg) (tea.Model, tea.Cmd) {
+	m.confirmOverwrite = &msg
+	m.diffTable.SetColumns(diffColumns(m.width - 8))
+	m.diffTable.SetRows(diffRows(msg.Changes))
+	m.diffTable.SetHeight(len(msg.Changes) + 1)
+	m.status = fmt.Sprintf("PO %s is already on file with different values.", msg.PO)
+	return m, nil
+}
+
+// updateConfirmOverwrite handles the answer to the re-parse diff.
+func (m model) updateConfirmOverwrite(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	pending := *m.confirmOverwrite
+	switch msg.String() {
+	case "t", "T":
+		m.confirmOverwrite = nil
+		m.batchReviewed("taken")
+		m.status = "Saving PO " + pending.PO + "..."
+		return m, saveParseResult(m.db, pending.PO, pending.PDF, pending.Order)
+	case "k", "K":
+		m.confirmOverwrite = nil
+		m.batchReviewed("kept")
+		m.status = "Kept the stored record for PO " + pending.PO + "."
+		return m, nil
+	case "esc":
+		m.confirmOverwrite = nil
+		m.batchReviewed("")
+		m.status = "Save canceled. Press R to save the new result anyway."
+		m.fail(failedOp{kind: retrySave, path: pending.PDF, po: pending.PO, order: pending.Order})
+		return m, nil
+	}
+	return m, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func order(t *testing.T, s string) PurchaseOrder {
+	t.Helper()
+	var po PurchaseOrder
+	if err := json.Unmarshal([]byte(s), &po); err != nil {
+		t.Fatal(err)
+	}
+	return po
+}
+
+func TestStoredChangesComparesFields(t *testing.T) {
+	db := testDB(t)
+	stored := order(t, `{"po_number": "PO-1", "vendor": "Acme", "total": 10, "items": [{"sku": "A", "quantity": 2}]}`)
+	if err := upsertPO(db, "PO-1", "/po1.pdf", stored); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err := storedChanges(db, "PO-1", "/po1.pdf", stored); err != nil || len(changes) != 0 {
+		t.Fatalf("same parse: changes %v, err %v", changes, err)
+	}
+
+	parsed := order(t, `{"po_number": "PO-1", "vendor": "Acme Ltd", "date": "2024-01-02", "items": [{"sku": "A", "quantity": 3}]}`)
+	changes, err := storedChanges(db, "PO-1", "/po1.pdf", parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []fieldChange{
+		{"~", "vendor", "Acme", "Acme Ltd"},
+		{"+", "date", "", "2024-01-02"},
+		{"-", "total", "10", ""},
+		{"~", "items.0.quantity", "2", "3"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes:\n got %v\nwant %v", changes, want)
+	}
+}
+
+func TestStoredChangesWithoutSavedFields(t *testing.T) {
+	db := testDB(t)
+	if _, err := db.Exec("INSERT INTO purchase_orders (po_number, pdf_path) VALUES ('PO-1', '/po1.pdf')"); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := storedChanges(db, "PO-1", "/po1.pdf", order(t, `{"po_number": "PO-1", "vendor": "Acme"}`))
+	if err != nil || len(changes) != 0 {
+		t.Errorf("changes %v, err %v; want none for a row saved before po_fields", changes, err)
+	}
+}
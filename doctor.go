@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// ----- Doctor -----
+
+// checkResult is one line of the doctor report. Optional checks warn
+// instead of failing, since the TUI works around what they cover.
+type checkResult struct {
+	name     string
+	ok       bool
+	optional bool
+	detail   string
+}
+
+func (c checkResult) String() string {
+	mark := "PASS"
+	switch {
+	case !c.ok && c.optional:
+		mark = "WARN"
+	case !c.ok:
+		mark = "FAIL"
+	}
+	return fmt.Sprintf("%s  %-8s %s", mark, c.name, c.detail)
+}
+
+// scriptCheck compiles the parser script without running it and lists the
+// top-level imports Python can't find. It prints nothing when all is well.
+const scriptCheck = `import ast, importlib.util, sys
+src = open(sys.argv[1]).read()
+tree = ast.parse(src, sys.argv[1])
+mods = {n.name.split(".")[0] for s in tree.body if isinstance(s, ast.Import) for n in s.names}
+mods |= {s.module.split(".")[0] for s in tree.body if isinstance(s, ast.ImportFrom) and s.module and not s.level}
+missing = sorted(m for m in mods if importlib.util.find_spec(m) is None)
+if missing:
+    print("missing modules: " + ", ".join(missing))
+`
+
+// runDoctorCommand implements "doctor [flags]": it checks the parser, the
+// database and the file picker and exits 1 if anything required is missing.
+func runDoctorCommand(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: pdf-parser doctor [flags]")
+		fs.PrintDefaults()
+	}
+	cfg, err := loadConfig(fs, args)
+	if err == flag.ErrHelp {
+		return 0
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var results []checkResult
+	if cfg.backend == "exec" {
+		results = append(results, checkCommand(cfg.parserCmd))
+	} else {
+		python := checkPython(cfg.pythonPath)
+		results = append(results, python, checkScript(cfg.pythonPath, cfg.scriptPath, python.ok))
+	}
+	results = append(results, checkDatabase(cfg), checkDialog())
+
+	code := 0
+	for _, r := range results {
+		fmt.Println(r)
+		if !r.ok && !r.optional {
+			code = 1
+		}
+	}
+	return code
+}
+
+func checkPython(python string) checkResult {
+	path, err := exec.LookPath(python)
+	if err != nil {
+		return checkResult{"python", false, false, python + " not found; install Python 3 or set PDFPARSER_PYTHON"}
+	}
+	out, err := exec.Command(path, "-c", "import sys; print('%d.%d.%d' % sys.version_info[:3])").Output()
+	if err != nil {
+		return checkResult{"python", false, false, path + " doesn't run: " + err.Error()}
+	}
+	version := strings.TrimSpace(string(out))
+	if !strings.HasPrefix(version, "3.") {
+		return checkResult{"python", false, false, path + " is Python " + version + "; the parser needs Python 3"}
+	}
+	return checkResult{"python", true, false, "Python " + version + " (" + path + ")"}
+}
+
+func checkScript(python, script string, havePython bool) checkResult {
+	if _, err := os.Stat(script); err != nil {
+		return checkResult{"script", false, false, script + " not found; pass -script or set PDFPARSER_SCRIPT"}
+	}
+	if !havePython {
+		return checkResult{"script", false, false, script + " can't be checked without Python"}
+	}
+	out, err := exec.Command(python, "-c", scriptCheck, script).CombinedOutput()
+	if err != nil {
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		return checkResult{"script", false, false, script + ": " + lines[len(lines)-1]}
+	}
+	if problem := strings.TrimSpace(string(out)); problem != "" {
+		return checkResult{"script", false, false, script + ": " + problem + " (see requirements.txt)"}
+	}
+	return checkResult{"script", true, false, script}
+}
+
+func checkCommand(command string) checkResult {
+	path, err := exec.LookPath(command)
+	if err != nil {
+		return checkResult{"parser", false, false, command + " not found or not executable"}
+	}
+	return checkResult{"parser", true, false, path}
+}
+
+// checkDatabase connects without creating anything, unlike openDatabase, and
+// looks for the columns every query relies on.
+func checkDatabase(cfg config) checkResult {
+	driver, source := cfg.dataSource()
+	label := cfg.databaseLabel()
+	if driver == "sqlite3" {
+		if _, ok := existingDatabase(source); !ok && !strings.HasPrefix(source, ":") && !strings.HasPrefix(source, "file:") {
+			return checkResult{"database", true, false, label + " doesn't exist yet; it will be created on first use"}
+		}
+	}
+	if err := checkDriver(driver); err != nil {
+		return checkResult{"database", false, false, err.Error()}
+	}
+	activeDialect = dialects[driver]
+	db, err := sql.Open(driver, source)
+	if err != nil {
+		return checkResult{"database", false, false, err.Error()}
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		return checkResult{"database", false, false, databaseError(label, err).Error()}
+	}
+	cols, err := tableColumns(db, "purchase_orders")
+	if err != nil {
+		return checkResult{"database", false, false, databaseError(label, err).Error()}
+	}
+	if len(cols) == 0 {
+		return checkResult{"database", false, false, label + " has no purchase_orders table; the TUI offers to create it"}
+	}
+	for _, want := range []string{"po_number", "pdf_path"} {
+		if !slices.Contains(cols, want) {
+			return checkResult{"database", false, false, label + ": purchase_orders has no " + want + " column"}
+		}
+	}
+	return checkResult{"database", true, false, label}
+}
+
+func checkDialog() checkResult {
+	cmd := fileDialogCommand()
+	if cmd == nil {
+		return checkResult{"dialog", false, true, "no file picker found (zenity on Linux); u and b will ask for a path instead"}
+	}
+	return checkResult{"dialog", true, true, cmd.Path}
+}
@@ -0,0 +1,6 @@
+//go:build mysql
+
+package main
+
+// Links in -driver mysql; build with -tags mysql.
+import _ "github.com/go-sql-driver/mysql"
@@ -0,0 +1,6 @@
+//go:build postgres
+
+package main
+
+// Links in -driver postgres; build with -tags postgres.
+import _ "github.com/lib/pq"
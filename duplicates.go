@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Duplicate PDFs -----
+
+// duplicateMsg means the parsed PDF is byte-for-byte a file already on
+// record under another path; the save waits for the user to link or skip.
+type duplicateMsg struct {
+	PO          string // parsed from the new file
+	PDF         string
+	Text        string
+	ExistingPO  string
+	ExistingPDF string
+}
+
+type linkResultMsg struct {
+	PO  string
+	PDF string
+	Err error
+}
+
+// initHashes adds pdf_hash to older databases.
+func initHashes(db *sql.DB) error {
+	has, err := hasColumn(db, "purchase_orders", "pdf_hash")
+	if err != nil || has {
+		return err
+	}
+	if _, err := db.Exec("ALTER TABLE purchase_orders ADD COLUMN pdf_hash TEXT"); err != nil {
+		return fmt.Errorf("DB schema error: %v", err)
+	}
+	return nil
+}
+
+// fileHash is the hex SHA-256 of the file at path.
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open error: %v", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("read error: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// findDuplicate looks for a stored PO whose PDF has the same contents as
+// pdfPath but lives somewhere else. ok is false when there's none.
+func findDuplicate(db *sql.DB, pdfPath string) (po, existing string, ok bool, err error) {
+	hash, err := fileHash(pdfPath)
+	if err != nil {
+		// Unreadable now means nothing to compare; the save reports the rest.
+		return "", "", false, nil
+	}
+	err = retryBusy(db, func() error {
+		return db.QueryRow(rebind("SELECT po_number, pdf_path FROM purchase_orders WHERE pdf_hash = ? AND pdf_path != ? LIMIT 1"),
+			hash, pdfPath).Scan(&po, &existing)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", false, nil
+	} else if err != nil {
+		if isBusy(err) {
+			return "", "", false, errDatabaseBusy
+		}
+		return "", "", false, fmt.Errorf("DB query error: %v", err)
+	}
+	return po, existing, true, nil
+}
+
+// linkDuplicate points the existing record at the new copy of its PDF.
+func linkDuplicate(db *sql.DB, po, pdfPath string) tea.Cmd {
+	return func() tea.Msg {
+		debugLog.Printf("db link: po=%q pdf=%s", po, pdfPath)
+		err := retryBusy(db, func() error {
+			_, err := db.Exec(rebind("UPDATE purchase_orders SET pdf_path = ? WHERE po_number = ?"), pdfPath, po)
+			return err
+		})
+		if isBusy(err) {
+			err = errDatabaseBusy
+		} else if err != nil {
+			err = fmt.Errorf("DB save error: %v", err)
+		}
+		return linkResultMsg{po, pdfPath, err}
+	}
+}
+
+// showDuplicate asks what to do with a PDF already on record.
+func (m model) showDuplicate(msg duplicateMsg) (tea.Model, tea.Cmd) {
+	m.confirmDuplicate = &msg
+	m.status = "This PDF is already on file as PO " + msg.ExistingPO + "."
+	return m, nil
+}
+
+// updateConfirmDuplicate handles the answer to the duplicate prompt.
+func (m model) updateConfirmDuplicate(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	pending := *m.confirmDuplicate
+	switch msg.String() {
+	case "l", "L":
+		m.confirmDuplicate = nil
+		m.batchReviewed("linked")
+		m.status = "Linking PO " + pending.ExistingPO + " to the new path..."
+		return m, linkDuplicate(m.db, pending.ExistingPO, pending.PDF)
+	case "s", "S", "esc":
+		m.confirmDuplicate = nil
+		m.batchReviewed("skipped")
+		m.status = "Skipped. PO " + pending.ExistingPO + " still points at " + pending.ExistingPDF + "."
+		return m, nil
+	}
+	return m, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Export -----
+
+type exportResultMsg struct {
+	Path string
+	Err  error
+}
+
+type csvExportResultMsg struct {
+	Path string
+	Err  error
+}
+
+// pendingExport is an export waiting on the user to confirm an overwrite.
+type pendingExport struct {
+	path string
+	csv  bool
+}
+
+// exportPath places the export next to the source PDF, swapping its extension.
+func exportPath(pdfPath, ext string) string {
+	return strings.TrimSuffix(pdfPath, filepath.Ext(pdfPath)) + ext
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// nextFreePath returns path with the first unused numeric suffix, e.g.
+// name-1.json, name-2.json.
+func nextFreePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if !fileExists(candidate) {
+			return candidate
+		}
+	}
+}
+
+func exportJSON(path string, po PurchaseOrder) tea.Cmd {
+	return func() tea.Msg {
+		output, err := json.MarshalIndent(po, "", "  ")
+		if err != nil {
+			return exportResultMsg{path, fmt.Errorf("Export error: %v", err)}
+		}
+		if err := os.WriteFile(path, append(output, '\n'), 0o644); err != nil {
+			return exportResultMsg{path, fmt.Errorf("Export error: %v", err)}
+		}
+		return exportResultMsg{path, nil}
+	}
+}
+
+func exportCSV(path string, po PurchaseOrder) tea.Cmd {
+	return func() tea.Msg {
+		// Like the table, the CSV leaves out the document text.
+		po.RawText = ""
+		parsed := po.Map()
+		f, err := os.Create(path)
+		if err != nil {
+			return csvExportResultMsg{path, fmt.Errorf("CSV export error: %v", err)}
+		}
+		defer f.Close()
+
+		w := csv.NewWriter(f)
+		w.Write([]string{"field", "value"})
+		for _, k := range orderedKeys(parsed) {
+			w.Write([]string{k, csvValue(parsed[k])})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return csvExportResultMsg{path, fmt.Errorf("CSV export error: %v", err)}
+		}
+		return csvExportResultMsg{path, nil}
+	}
+}
+
+// csvValue renders a value for a single cell. Nested objects and arrays are
+// JSON-encoded so they still fit in one column.
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// runExport writes to path, or asks first when that would replace a file.
+func (m model) runExport(path string, csv bool) (tea.Model, tea.Cmd) {
+	if fileExists(path) {
+		m.confirmExport = &pendingExport{path: path, csv: csv}
+		m.status = "File exists. Overwrite? (y/n, esc to cancel)"
+		return m, nil
+	}
+	if csv {
+		return m, exportCSV(path, m.result)
+	}
+	return m, exportJSON(path, m.result)
+}
+
+// updateConfirmExport answers the overwrite prompt: y replaces the file, n
+// writes to a numbered sibling instead.
+func (m model) updateConfirmExport(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	pending := *m.confirmExport
+	switch msg.String() {
+	case "y", "Y":
+	case "n", "N":
+		pending.path = nextFreePath(pending.path)
+	case "esc":
+		m.confirmExport = nil
+		m.status = "Export canceled."
+		return m, nil
+	default:
+		return m, nil
+	}
+	m.confirmExport = nil
+	if pending.csv {
+		return m, exportCSV(pending.path, m.result)
+	}
+	return m, exportJSON(pending.path, m.result)
+}
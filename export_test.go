@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportCSVLeavesOutRawText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "po.csv")
+	po := PurchaseOrder{PONumber: "PO-1", RawText: "the whole document"}
+	if msg := exportCSV(path, po)().(csvExportResultMsg); msg.Err != nil {
+		t.Fatal(msg.Err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); strings.Contains(got, "_raw_text") || !strings.Contains(got, "po_number,PO-1") {
+		t.Errorf("CSV = %q", got)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Extraction Options -----
+
+// ocrModes are the -ocr values: auto leaves it to the script, which only
+// OCRs pages with too little text; on and off force it either way.
+var ocrModes = []string{"auto", "on", "off"}
+
+const defaultOCR = "auto"
+
+// extractArgs are the parser arguments for the chosen extraction options.
+// The exec backend gets them too.
+func (o parserOptions) extractArgs() []string {
+	var args []string
+	switch o.OCR {
+	case "on":
+		args = append(args, "--ocr")
+	case "off":
+		args = append(args, "--no-ocr")
+	}
+	if o.Template != "" {
+		args = append(args, "--template", o.Template)
+	}
+	return args
+}
+
+// cycleOCR steps through ocrModes for the next parse.
+func (m *model) cycleOCR() tea.Cmd {
+	next := 0
+	for i, mode := range ocrModes {
+		if mode == firstNonEmpty(m.parser.OCR, defaultOCR) {
+			next = (i + 1) % len(ocrModes)
+		}
+	}
+	m.parser.OCR = ocrModes[next]
+	return m.notify("OCR "+m.parser.OCR+". Press ctrl+r to re-parse.", noticeTTL)
+}
+
+// toggleTemplate switches between the -template prompt and the script's own.
+func (m *model) toggleTemplate() tea.Cmd {
+	if m.template == "" {
+		m.status = "No prompt template configured. Start with -template to use one."
+		return nil
+	}
+	if m.parser.Template == "" {
+		m.parser.Template = m.template
+	} else {
+		m.parser.Template = ""
+	}
+	return m.notify("Prompt: "+m.templateName()+". Press ctrl+r to re-parse.", noticeTTL)
+}
+
+func (m model) templateName() string {
+	if m.parser.Template == "" {
+		return "built-in"
+	}
+	return filepath.Base(m.parser.Template)
+}
+
+// extractionNote shows the options when they differ from the defaults, so
+// it's clear how the result on screen was extracted.
+func (m model) extractionNote() string {
+	var parts []string
+	if ocr := firstNonEmpty(m.parser.OCR, defaultOCR); ocr != defaultOCR {
+		parts = append(parts, "OCR "+ocr)
+	}
+	if m.parser.Template != "" {
+		parts = append(parts, "template "+m.templateName())
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Extraction: " + strings.Join(parts, ", ")
+}
+
+// reparse runs the parser again on the last file with the current options.
+func (m model) reparse() (tea.Model, tea.Cmd) {
+	path := firstNonEmpty(m.lastParsed, m.uploadPath)
+	switch {
+	case m.loading():
+		m.status = "Busy. Wait for the current job or press esc to cancel."
+		return m, nil
+	case path == "":
+		m.status = "Nothing to re-parse. Press u to pick a PDF."
+		return m, nil
+	}
+	m.activeTab = tabUpload
+	m.showRaw, m.showText, m.showItems = false, false, false
+	m.begin()
+	debugLog.Printf("re-parse: %s %s", path, strings.Join(m.parser.extractArgs(), " "))
+	return m, tea.Batch(func() tea.Msg { return fileSelectedMsg(path) }, m.spinner.Tick)
+}
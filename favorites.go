@@ -0,0 +1,91 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Favorites -----
+
+// Favorites live in their own table rather than a purchase_orders column, so
+// app.py's schema is left as it is.
+const favoritesSchema = `CREATE TABLE IF NOT EXISTS favorites (
+	po_number TEXT PRIMARY KEY
+)`
+
+// favoriteMark flags a favorite in the list's last column.
+const favoriteMark = "★"
+
+type favoriteResultMsg struct {
+	PO       string
+	Favorite bool // the PO's state after the toggle
+	Err      error
+}
+
+// toggleFavorite pins po, or unpins it if it's already a favorite.
+func toggleFavorite(db *sql.DB, po string) tea.Cmd {
+	return func() tea.Msg {
+		debugLog.Printf("db favorite toggle: po=%q", po)
+		var favorite bool
+		err := retryBusy(db, func() error {
+			res, err := db.Exec(rebind("DELETE FROM favorites WHERE po_number = ?"), po)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				favorite = false
+				return nil
+			}
+			favorite = true
+			_, err = db.Exec(rebind("INSERT INTO favorites (po_number) VALUES (?)"), po)
+			return err
+		})
+		if isBusy(err) {
+			return favoriteResultMsg{po, false, errDatabaseBusy}
+		} else if err != nil {
+			return favoriteResultMsg{po, false, fmt.Errorf("DB save error: %v", err)}
+		}
+		return favoriteResultMsg{po, favorite, nil}
+	}
+}
+
+// favoritePO is the PO f acts on: the selected list row, or on the search tab
+// the selected partial match or the exact match.
+func (m model) favoritePO() string {
+	switch m.activeTab {
+	case tabList:
+		if row := m.listTable.SelectedRow(); row != nil && !m.listLoading {
+			return row[0]
+		}
+	case tabSearch:
+		if m.hasCandidates() {
+			return m.searchTable.SelectedRow()[0]
+		}
+		if m.pdfPath != "" {
+			// An exact match is the PO that was searched for.
+			return m.lastQuery
+		}
+	}
+	return ""
+}
+
+// handleFavoriteResult reports the toggle and refreshes the list so its marks
+// and the favorites-only filter match the database.
+func (m model) handleFavoriteResult(msg favoriteResultMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.Err != nil:
+		m.status = msg.Err.Error()
+	case msg.Favorite:
+		m.status = "Pinned PO " + msg.PO + "."
+	default:
+		m.status = "Unpinned PO " + msg.PO + "."
+	}
+	if m.activeTab != tabList {
+		return m, nil
+	}
+	m.listNote = m.status
+	cmd := m.loadList(m.listPage)
+	return m, cmd
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+)
+
+// ----- Field Layout -----
+
+const (
+	defaultFieldWidth = 15
+	minColumnWidth    = 8
+	// cellPadding is the horizontal padding tableStyles puts around each
+	// cell.
+	cellPadding = 2
+)
+
+// fieldColumns sizes the Field/Value columns so Value fills width. A width of
+// 0 (the terminal size isn't known yet) falls back to a fixed layout.
+func fieldColumns(width, fieldWidth int) []table.Column {
+	valueWidth := 30
+	if width > 0 {
+		fieldWidth = min(fieldWidth, width-2*cellPadding-minColumnWidth)
+		valueWidth = max(width-2*cellPadding-max(fieldWidth, minColumnWidth), minColumnWidth)
+	}
+	fieldWidth = max(fieldWidth, minColumnWidth)
+	return []table.Column{
+		{Title: "Field", Width: fieldWidth},
+		{Title: "Value", Width: valueWidth},
+	}
+}
+
+// preferredFieldOrder lists the fields shown first, in this order; anything
+// else follows alphabetically.
+var preferredFieldOrder = []string{"po_number", "vendor", "invoice_number", "date", "total"}
+
+// orderedKeys gives a stable key order so the table doesn't reshuffle on
+// every parse the way Go map iteration would.
+func orderedKeys(m map[string]interface{}) []string {
+	rank := make(map[string]int, len(preferredFieldOrder))
+	for i, k := range preferredFieldOrder {
+		rank[k] = i
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, iok := rank[keys[i]]
+		rj, jok := rank[keys[j]]
+		switch {
+		case iok && jok:
+			return ri < rj
+		case iok != jok:
+			return iok
+		default:
+			return keys[i] < keys[j]
+		}
+	})
+	return keys
+}
+
+// maxFlattenDepth stops flattening runaway nesting; anything deeper is shown
+// as JSON in a single cell.
+const maxFlattenDepth = 6
+
+type field struct {
+	Key   string
+	Value interface{}
+}
+
+// flattenFields turns nested objects into dotted keys (shipping.address) and
+// arrays into indexed keys (items.0.sku), keeping document order stable.
+func flattenFields(parsed map[string]interface{}) []field {
+	var out []field
+	for _, k := range orderedKeys(parsed) {
+		out = appendFlattened(out, k, parsed[k], 1)
+	}
+	return out
+}
+
+func appendFlattened(out []field, key string, v interface{}, depth int) []field {
+	if depth >= maxFlattenDepth {
+		return append(out, field{key, csvValue(v)})
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return append(out, field{key, "{}"})
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = appendFlattened(out, key+"."+k, v[k], depth+1)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return append(out, field{key, "[]"})
+		}
+		for i, item := range v {
+			out = appendFlattened(out, fmt.Sprintf("%s.%d", key, i), item, depth+1)
+		}
+	default:
+		out = append(out, field{key, v})
+	}
+	return out
+}
+
+// recordRows flattens one streamed JSONL record under records.<i>, matching
+// the rows the final {"records": [...]} result will produce.
+func recordRows(i int, record map[string]interface{}, formats fieldFormats) []table.Row {
+	var rows []table.Row
+	for _, f := range appendFlattened(nil, fmt.Sprintf("records.%d", i), record, 1) {
+		rows = append(rows, table.Row{f.Key, formats.format(f.Key, f.Value)})
+	}
+	return rows
+}
+
+// fieldRows builds the field/value rows for the results table. The full
+// document text would swamp the table, so it's left out. Values are shown
+// through formats; see format.go.
+func fieldRows(po PurchaseOrder, formats fieldFormats) []table.Row {
+	po.RawText = ""
+	fields := flattenFields(po.Map())
+	rows := make([]table.Row, 0, len(fields))
+	for _, f := range fields {
+		rows = append(rows, table.Row{f.Key, formats.format(f.Key, f.Value)})
+	}
+	return rows
+}
+
+// noFields reports whether rows hold nothing worth a table: no rows at all,
+// or only blank values and empty objects or lists.
+func noFields(rows []table.Row) bool {
+	for _, r := range rows {
+		if v := strings.TrimSpace(r[1]); v != "" && v != "{}" && v != "[]" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderedKeys(t *testing.T) {
+	m := map[string]interface{}{
+		"zeta": 1, "total": 2, "alpha": 3, "po_number": 4, "date": 5, "vendor": 6,
+	}
+	want := []string{"po_number", "vendor", "date", "total", "alpha", "zeta"}
+	for i := 0; i < 20; i++ { // map iteration order varies between runs
+		if got := orderedKeys(m); !reflect.DeepEqual(got, want) {
+			t.Fatalf("orderedKeys = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestFlattenFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		parsed map[string]interface{}
+		want   []field
+	}{
+		{
+			name: "nested map",
+			parsed: map[string]interface{}{
+				"po_number": "PO-1",
+				"shipping":  map[string]interface{}{"city": "Oslo", "address": map[string]interface{}{"zip": "0150"}},
+			},
+			want: []field{{"po_number", "PO-1"}, {"shipping.address.zip", "0150"}, {"shipping.city", "Oslo"}},
+		},
+		{
+			name: "items indexed",
+			parsed: map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"sku": "A", "quantity": 2.0},
+					map[string]interface{}{"sku": "B"},
+				},
+			},
+			want: []field{{"items.0.quantity", 2.0}, {"items.0.sku", "A"}, {"items.1.sku", "B"}},
+		},
+		{
+			name:   "empty containers",
+			parsed: map[string]interface{}{"items": []interface{}{}, "notes": map[string]interface{}{}},
+			want:   []field{{"items", "[]"}, {"notes", "{}"}},
+		},
+		{
+			name:   "depth cap",
+			parsed: map[string]interface{}{"a": nest(maxFlattenDepth+2, "deep")},
+			want:   []field{{"a.b.b.b.b.b", `{"b":{"b":{"b":"deep"}}}`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flattenFields(tt.parsed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flattenFields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// nest wraps v in n levels of {"b": ...}.
+func nest(n int, v interface{}) interface{} {
+	for i := 0; i < n; i++ {
+		v = map[string]interface{}{"b": v}
+	}
+	return v
+}
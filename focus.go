@@ -0,0 +1,48 @@
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// ----- Search Focus -----
+
+// The search tab is either editing the query, with every printable key and
+// ←/→ going to the input, or browsing the results, where ↑/↓ and Enter act
+// on the table and the one-letter keys work again. esc leaves the input and
+// / goes back to it.
+
+// typingSearch reports whether msg is text for the focused search input, to
+// be passed to it before any binding sees it.
+func (m model) typingSearch(msg tea.KeyMsg) bool {
+	if m.activeTab != tabSearch || !m.searchInput.Focused() {
+		return false
+	}
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace, tea.KeyLeft, tea.KeyRight:
+		return true
+	}
+	return false
+}
+
+// browseResults leaves the input so the keys act on the results.
+func (m *model) browseResults() {
+	m.searchInput.Blur()
+	m.vendorPick = -1
+	m.status = "Press / to edit the search."
+}
+
+func (m *model) editSearch() tea.Cmd {
+	m.status = tabPrompts[tabSearch]
+	return m.searchInput.Focus()
+}
+
+// searchFocusHint says where the keys are going while results are on
+// screen.
+func (m model) searchFocusHint() string {
+	if !m.hasCandidates() {
+		return ""
+	}
+	hint := "Editing the search — esc to browse the results."
+	if !m.searchInput.Focused() {
+		hint = "Browsing results — / to edit the search."
+	}
+	return "\n" + m.styles.CenterText.Width(m.width).Render(hint)
+}
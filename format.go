@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----- Value Formatting -----
+
+// fieldFormats maps a field to how the results table shows it. A key matches
+// a flattened field exactly (items.0.total) or its last part (total), so one
+// entry covers every line item. Formatting is display-only: exports, copies
+// and the database keep the parser's values.
+type fieldFormats map[string]string
+
+// defaultFormats applies when neither -formats nor the config file set any.
+const defaultFormats = "total=currency,unit_price=currency,date=date"
+
+// formatters turn a value into its display form, reporting false when the
+// value doesn't fit (a date that won't parse), so it's shown as it came.
+var formatters = map[string]func(v interface{}) (string, bool){
+	"currency": func(v interface{}) (string, bool) {
+		f, ok := number(v)
+		if !ok {
+			return "", false
+		}
+		return groupThousands(strconv.FormatFloat(f, 'f', 2, 64)), true
+	},
+	"number": func(v interface{}) (string, bool) {
+		f, ok := number(v)
+		if !ok {
+			return "", false
+		}
+		return groupThousands(strconv.FormatFloat(f, 'f', -1, 64)), true
+	},
+	"date": func(v interface{}) (string, bool) {
+		s, ok := v.(string)
+		if !ok {
+			return "", false
+		}
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+				return t.Format("2006-01-02"), true
+			}
+		}
+		return "", false
+	},
+}
+
+// dateLayouts are the date forms the date format recognizes. Numeric dates
+// are read month first, as US purchase orders write them.
+var dateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006/01/02",
+	"01/02/2006",
+	"1/2/2006",
+	"01-02-2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	"02-Jan-2006",
+}
+
+// formatNames lists the formats for flag help and errors.
+func formatNames() string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parseFormats reads a -formats value such as "total=currency,date=date".
+// "none" turns formatting off.
+func parseFormats(spec string) (fieldFormats, error) {
+	if strings.TrimSpace(spec) == "none" {
+		return nil, nil
+	}
+	formats := fieldFormats{}
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, kind, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("bad -formats entry %q (want field=format)", pair)
+		}
+		formats[strings.TrimSpace(key)] = strings.TrimSpace(kind)
+	}
+	return formats, formats.check()
+}
+
+// check reports a format name formatters doesn't know.
+func (f fieldFormats) check() error {
+	for key, kind := range f {
+		if _, ok := formatters[kind]; !ok {
+			return fmt.Errorf("unknown format %q for %s (choose from %s)", kind, key, formatNames())
+		}
+	}
+	return nil
+}
+
+// format renders v, the value of the flattened field key, for the table.
+func (f fieldFormats) format(key string, v interface{}) string {
+	kind, ok := f[key]
+	if !ok {
+		kind, ok = f[key[strings.LastIndex(key, ".")+1:]]
+	}
+	if ok {
+		if s, ok := formatters[kind](v); ok {
+			return s
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// number reads a numeric value, including one the parser sent as a string.
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", ""), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// groupThousands puts commas into the integer part of a formatted number.
+func groupThousands(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if hasFrac {
+		return sign + b.String() + "." + frac
+	}
+	return sign + b.String()
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ----- Full-Text Search -----
+
+// The FTS5 index mirrors purchase_orders.pdf_text through triggers, so every
+// writer (including app.py) keeps it current. go-sqlite3 only ships FTS5 when
+// built with -tags sqlite_fts5; without it, text search scans with LIKE.
+var ftsSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS po_text_fts USING fts5(pdf_text, content='purchase_orders')`,
+	`CREATE TRIGGER IF NOT EXISTS po_text_ai AFTER INSERT ON purchase_orders BEGIN
+		INSERT INTO po_text_fts(rowid, pdf_text) VALUES (new.rowid, new.pdf_text);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS po_text_ad AFTER DELETE ON purchase_orders BEGIN
+		INSERT INTO po_text_fts(po_text_fts, rowid, pdf_text) VALUES ('delete', old.rowid, old.pdf_text);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS po_text_au AFTER UPDATE ON purchase_orders BEGIN
+		INSERT INTO po_text_fts(po_text_fts, rowid, pdf_text) VALUES ('delete', old.rowid, old.pdf_text);
+		INSERT INTO po_text_fts(rowid, pdf_text) VALUES (new.rowid, new.pdf_text);
+	END`,
+}
+
+// initFullText adds the pdf_text column to older databases and builds the
+// FTS5 index when SQLite supports it. Missing FTS5, or another database, is
+// not an error.
+func initFullText(db *sql.DB) error {
+	has, err := hasColumn(db, "purchase_orders", "pdf_text")
+	if err != nil {
+		return err
+	}
+	if !has {
+		// Adding a nullable column leaves every existing row as it was.
+		if _, err := db.Exec("ALTER TABLE purchase_orders ADD COLUMN pdf_text TEXT"); err != nil {
+			return fmt.Errorf("DB schema error: %v", err)
+		}
+	}
+	if !activeDialect.fullText {
+		return nil
+	}
+
+	existed, err := hasFTS(db)
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("DB schema error: %v", err)
+	}
+	defer tx.Rollback()
+	for _, stmt := range ftsSchema {
+		if _, err := tx.Exec(stmt); err != nil {
+			if strings.Contains(err.Error(), "no such module: fts5") {
+				debugLog.Printf("db: fts5 unavailable, text search will use LIKE")
+				return nil
+			}
+			return fmt.Errorf("DB schema error: %v", err)
+		}
+	}
+	if !existed {
+		// Index text saved before the index existed.
+		if _, err := tx.Exec("INSERT INTO po_text_fts(po_text_fts) VALUES ('rebuild')"); err != nil {
+			return fmt.Errorf("DB schema error: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	cols, err := tableColumns(db, table)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range cols {
+		if strings.EqualFold(name, column) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hasTable reports whether table exists. A -readonly database skips schema
+// setup, so one made by app.py or an older build may lack newer tables.
+func hasTable(db *sql.DB, table string) (bool, error) {
+	cols, err := tableColumns(db, table)
+	return len(cols) > 0, err
+}
+
+func hasFTS(db *sql.DB) (bool, error) {
+	if !activeDialect.fullText {
+		return false, nil
+	}
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'po_text_fts'").Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("DB query error: %v", err)
+	}
+	return n > 0, nil
+}
+
+// searchText finds POs whose document text contains query, each with a short
+// snippet around the hit.
+func searchText(db *sql.DB, query string) ([]poMatch, error) {
+	fts, err := hasFTS(db)
+	if err != nil {
+		return nil, err
+	}
+	if fts {
+		return searchTextFTS(db, query)
+	}
+	return searchTextLike(db, query)
+}
+
+func searchTextFTS(db *sql.DB, query string) ([]poMatch, error) {
+	// Quote the input as one phrase so characters like # or - aren't read as
+	// FTS5 query syntax.
+	phrase := `"` + strings.ReplaceAll(query, `"`, `""`) + `"`
+	rows, err := db.Query(`SELECT p.po_number, p.pdf_path, snippet(po_text_fts, 0, '[', ']', '…', 10)
+		FROM po_text_fts JOIN purchase_orders p ON p.rowid = po_text_fts.rowid
+		WHERE po_text_fts MATCH ? ORDER BY rank LIMIT ?`, phrase, maxSearchResults)
+	if err != nil {
+		return nil, fmt.Errorf("DB query error: %v", err)
+	}
+	return scanSnippets(rows, nil)
+}
+
+func searchTextLike(db *sql.DB, query string) ([]poMatch, error) {
+	has, err := hasColumn(db, "purchase_orders", "pdf_text")
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, fmt.Errorf("This database has no document text to search.")
+	}
+	rows, err := db.Query(rebind("SELECT po_number, pdf_path, pdf_text FROM purchase_orders WHERE pdf_text "+activeDialect.like+" ORDER BY po_number LIMIT ?"),
+		likeContains(query), maxSearchResults)
+	if err != nil {
+		return nil, fmt.Errorf("DB query error: %v", err)
+	}
+	return scanSnippets(rows, func(text string) string { return textSnippet(text, query) })
+}
+
+// scanSnippets reads po/pdf/text rows; trim, when set, cuts the text down to
+// a snippet.
+func scanSnippets(rows *sql.Rows, trim func(string) string) ([]poMatch, error) {
+	defer rows.Close()
+	var matches []poMatch
+	for rows.Next() {
+		var m poMatch
+		var text sql.NullString
+		if err := rows.Scan(&m.PO, &m.PDF, &text); err != nil {
+			return nil, fmt.Errorf("DB scan error: %v", err)
+		}
+		m.Snippet = text.String
+		if trim != nil {
+			m.Snippet = trim(m.Snippet)
+		}
+		m.Snippet = strings.Join(strings.Fields(m.Snippet), " ")
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("DB query error: %v", err)
+	}
+	return matches, nil
+}
+
+// snippetRadius is how many bytes of context textSnippet keeps either side.
+const snippetRadius = 30
+
+// textSnippet mimics FTS5's snippet() for the LIKE fallback, bracketing the
+// first case-insensitive hit.
+func textSnippet(text, query string) string {
+	i := strings.Index(strings.ToLower(text), strings.ToLower(query))
+	// Lowercasing can change byte lengths for some scripts; bail rather than
+	// slice out of range.
+	if i < 0 || query == "" || i+len(query) > len(text) {
+		return ""
+	}
+	start, end := i-snippetRadius, i+len(query)+snippetRadius
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(text) {
+		end, suffix = len(text), ""
+	}
+	// Don't cut through a multi-byte character.
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	return prefix + text[start:i] + "[" + text[i:i+len(query)] + "]" + text[i+len(query):end] + suffix
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ----- Match Highlighting -----
+
+// The table truncates cells by byte-counting width, so styled values would be
+// cut short; matches are highlighted in the rendered view instead.
+
+// tableHeaderLines is how many lines a table header takes; see tableStyles.
+var tableHeaderLines = lipgloss.Height(plainStyles().Table.Header.Render("x"))
+
+// highlightTable styles every case-insensitive occurrence of term in the
+// rows of a rendered table, leaving the header alone.
+func highlightTable(view, term string, style lipgloss.Style) string {
+	if term == "" {
+		return view
+	}
+	lines := strings.Split(view, "\n")
+	for i := tableHeaderLines; i < len(lines); i++ {
+		lines[i] = highlightLine(lines[i], term, style)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// highlightLine restyles the matched spans of a line that may already carry
+// styling, such as the selected row's. The line's own escape codes are kept
+// after each span so its styling resumes.
+func highlightLine(line, term string, style lipgloss.Style) string {
+	plain := []rune(ansi.Strip(line))
+	spans := foldIndex(plain, []rune(term))
+	if len(spans) == 0 {
+		return line
+	}
+	var b strings.Builder
+	done := 0 // cells of line already written
+	for _, start := range spans {
+		from := ansi.StringWidth(string(plain[:start]))
+		to := ansi.StringWidth(string(plain[:start+len([]rune(term))]))
+		b.WriteString(ansi.Cut(line, done, from))
+		b.WriteString(style.Render(string(plain[start : start+len([]rune(term))])))
+		done = to
+	}
+	b.WriteString(ansi.TruncateLeft(line, done, ""))
+	return b.String()
+}
+
+// foldIndex finds the non-overlapping case-insensitive occurrences of term in
+// s, as rune offsets.
+func foldIndex(s, term []rune) []int {
+	var found []int
+	for i := 0; len(term) > 0 && i+len(term) <= len(s); i++ {
+		match := true
+		for j, r := range term {
+			if unicode.ToLower(s[i+j]) != unicode.ToLower(r) {
+				match = false
+				break
+			}
+		}
+		if match {
+			found = append(found, i)
+			i += len(term) - 1
+		}
+	}
+	return found
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Search History -----
+
+const maxHistory = 50
+
+func historyFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "search_history"), nil
+}
+
+// loadHistory reads saved queries, oldest first. A missing file just means no
+// history yet.
+func loadHistory() []string {
+	path, err := historyFile()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			history = append(history, line)
+		}
+	}
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	return history
+}
+
+// saveHistory writes the history in the background; failing to persist it
+// isn't worth interrupting a search over.
+func saveHistory(history []string) tea.Cmd {
+	data := strings.Join(history, "\n") + "\n"
+	return func() tea.Msg {
+		path, err := historyFile()
+		if err != nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil
+		}
+		os.WriteFile(path, []byte(data), 0o644)
+		return nil
+	}
+}
+
+// addToHistory appends query as the most recent entry, dropping any earlier
+// copy and the oldest entries beyond maxHistory.
+func addToHistory(history []string, query string) []string {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return history
+	}
+	out := make([]string, 0, len(history)+1)
+	for _, h := range history {
+		if h != query {
+			out = append(out, h)
+		}
+	}
+	out = append(out, query)
+	if len(out) > maxHistory {
+		out = out[len(out)-maxHistory:]
+	}
+	return out
+}
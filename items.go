@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+)
+
+// ----- Line Items -----
+
+// itemsColumns gives Description whatever width the fixed columns leave.
+func itemsColumns(width int) []table.Column {
+	cols := []table.Column{
+		{Title: "SKU", Width: 12},
+		{Title: "Description", Width: 30},
+		{Title: "Qty", Width: 6},
+		{Title: "Unit price", Width: 12},
+		{Title: "Total", Width: 12},
+	}
+	if width > 0 {
+		fixed := 0
+		for i, c := range cols {
+			if i != 1 {
+				fixed += c.Width
+			}
+		}
+		cols[1].Width = max(width-fixed-len(cols)*cellPadding, minColumnWidth)
+	}
+	return cols
+}
+
+// itemRows lays out po.Items, with numbers shown through formats like the
+// summary's items.N fields.
+func itemRows(items []LineItem, formats fieldFormats) []table.Row {
+	rows := make([]table.Row, 0, len(items))
+	for _, it := range items {
+		rows = append(rows, table.Row{
+			it.SKU,
+			it.Description,
+			itemNumber(it, it.Quantity, "quantity", formats),
+			itemNumber(it, it.UnitPrice, "unit_price", formats),
+			itemNumber(it, it.Total, "total", formats),
+		})
+	}
+	return rows
+}
+
+// itemNumber leaves a missing value blank; a 0 the parser sent is shown.
+func itemNumber(it LineItem, v float64, field string, formats fieldFormats) string {
+	if v == 0 && !it.has(field) {
+		return ""
+	}
+	return formats.format(field, v)
+}
+
+// openItems switches the upload tab to the line-items table, starting on the
+// item whose items.N field is selected in the summary.
+func (m *model) openItems() {
+	if len(m.result.Items) == 0 {
+		m.status = "This PO has no line items."
+		return
+	}
+	m.itemsTable.SetRows(itemRows(m.result.Items, m.formats))
+	m.itemsTable.GotoTop()
+	if row := m.table.SelectedRow(); row != nil {
+		if rest, ok := strings.CutPrefix(row[0], "items."); ok {
+			n, _, _ := strings.Cut(rest, ".")
+			if i, err := strconv.Atoi(n); err == nil {
+				m.itemsTable.SetCursor(i)
+			}
+		}
+	}
+	m.showItems = true
+	m.status = strconv.Itoa(len(m.result.Items)) + " line item(s). Press esc to return to the summary."
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+)
+
+// ----- Debug Log -----
+
+// debugLog discards everything unless -log is given. *log.Logger is safe to
+// use from the goroutines that run commands.
+var debugLog = log.New(io.Discard, "", 0)
+
+// openLog starts writing timestamped entries to path, appending to any
+// existing log so several sessions can be attached to one bug report.
+func openLog(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	debugLog = log.New(f, "", log.LstdFlags|log.Lmicroseconds)
+	return f, nil
+}
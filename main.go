@@ -1,56 +1,144 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
-	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
 	textinput "github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // ----- Styling -----
-var (
-	colorBackground = lipgloss.Color("#000000") // black
-	colorText       = lipgloss.Color("#00ff00") // matrix green
-	colorAccent     = lipgloss.Color("#00ff00") // matrix green accent
-	borderStyle     = lipgloss.ThickBorder()
-	styleBase       = lipgloss.NewStyle().Background(colorBackground).Foreground(colorText)
-	styleBox        = styleBase.Border(borderStyle, true).BorderForeground(colorAccent).Padding(1, 2)
-	styleTitle      = styleBase.Bold(true).Foreground(colorAccent).Align(lipgloss.Center)
-	styleCenterText = styleBase.Align(lipgloss.Center)
-)
+// Colors come from the active theme; see theme.go.
+var borderStyle = lipgloss.ThickBorder()
 
 // ----- Key Bindings -----
 type keyMap struct {
-	Upload key.Binding
-	Search key.Binding
-	Quit   key.Binding
+	Upload  key.Binding
+	Batch   key.Binding
+	Search  key.Binding
+	Lookup  key.Binding
+	Field   key.Binding
+	List    key.Binding
+	Theme   key.Binding
+	Raw     key.Binding
+	Text    key.Binding
+	Cancel  key.Binding
+	Export  key.Binding
+	CSV     key.Binding
+	Copy    key.Binding
+	CopyAll key.Binding
+	Share   key.Binding
+	Errors  key.Binding
+	Reopen  key.Binding
+	Folder  key.Binding
+	Delete  key.Binding
+	Pin     key.Binding
+	Pinned  key.Binding
+	Retry   key.Binding
+	Preview key.Binding
+	PgNext  key.Binding
+	PgPrev  key.Binding
+	Stats   key.Binding
+	Narrow  key.Binding
+	Widen   key.Binding
+	Longer  key.Binding
+	Shorter key.Binding
+	OCR     key.Binding
+	Tmpl    key.Binding
+	Reparse key.Binding
+	About   key.Binding
+	Clear   key.Binding
+	Detail  key.Binding
+	Quit    key.Binding
+	Help    key.Binding
+	Next    key.Binding
+	Prev    key.Binding
+
+	// Context-specific keys handled inline in Update; listed here for help only.
+	Enter    key.Binding
+	Open     key.Binding
+	Navigate key.Binding
+	Jump     key.Binding
+	Cell     key.Binding
+	Focus    key.Binding
 }
 
 var keys = keyMap{
-	Upload: key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "upload PDF")),
-	Search: key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "search PO")),
-	Quit:   key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit")),
+	Upload:  key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "upload PDF")),
+	Batch:   key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "batch folder")),
+	Search:  key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "search PO")),
+	Lookup:  key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "search parsed PO")),
+	Field:   key.NewBinding(key.WithKeys("ctrl+f"), key.WithHelp("ctrl+f", "search field")),
+	List:    key.NewBinding(key.WithKeys("l"), key.WithHelp("l", "list all")),
+	Theme:   key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "next theme")),
+	Raw:     key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "raw JSON / table")),
+	Text:    key.NewBinding(key.WithKeys("T"), key.WithHelp("T", "extracted text")),
+	Cancel:  key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "cancel parse")),
+	Export:  key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "export JSON")),
+	CSV:     key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "export CSV")),
+	Copy:    key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy value / path")),
+	CopyAll: key.NewBinding(key.WithKeys("Y"), key.WithHelp("Y", "copy all as JSON")),
+	Share:   key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "copy parse command")),
+	Errors:  key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "error details")),
+	Reopen:  key.NewBinding(key.WithKeys("O"), key.WithHelp("O", "open last PDF")),
+	Folder:  key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "open PDF's folder")),
+	Delete:  key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete PO")),
+	Pin:     key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "pin/unpin PO")),
+	Pinned:  key.NewBinding(key.WithKeys("F"), key.WithHelp("F", "favorites only")),
+	Retry:   key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "retry failed")),
+	Preview: key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "preview mode")),
+	PgNext:  key.NewBinding(key.WithKeys("]", "pgdown"), key.WithHelp("]", "next page")),
+	PgPrev:  key.NewBinding(key.WithKeys("[", "pgup"), key.WithHelp("[", "previous page")),
+	Stats:   key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "parse stats")),
+	Narrow:  key.NewBinding(key.WithKeys("<"), key.WithHelp("<", "narrow field column")),
+	Widen:   key.NewBinding(key.WithKeys(">"), key.WithHelp(">", "widen field column")),
+	Longer:  key.NewBinding(key.WithKeys("+"), key.WithHelp("+", "parse timeout +5s")),
+	Shorter: key.NewBinding(key.WithKeys("-"), key.WithHelp("-", "parse timeout -5s")),
+	OCR:     key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("ctrl+o", "OCR auto/on/off")),
+	Tmpl:    key.NewBinding(key.WithKeys("ctrl+t"), key.WithHelp("ctrl+t", "prompt template on/off")),
+	Reparse: key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "re-parse with these options")),
+	About:   key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "version")),
+	Clear:   key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "clear tab")),
+	Detail:  key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "full cell value")),
+	Quit:    key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+	Help:    key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "all keys")),
+	Next:    key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next tab")),
+	Prev:    key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "previous tab")),
+
+	Enter:    key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "search / pick / line items")),
+	Open:     key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open PDF")),
+	Navigate: key.NewBinding(key.WithKeys("up", "down"), key.WithHelp("↑/↓", "move / history")),
+	Jump:     key.NewBinding(key.WithKeys("g", "G", "home", "end", "ctrl+u", "ctrl+d"), key.WithHelp("g/G ctrl+u/d", "first/last row, half page")),
+	Cell:     key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←/→", "pick cell (with w)")),
+	Focus:    key.NewBinding(key.WithKeys("esc", "/"), key.WithHelp("esc, /", "browse results, edit search")),
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Upload, k.Search, k.Quit}
+	return []key.Binding{k.Upload, k.Search, k.List, k.Help, k.Quit}
 }
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Upload, k.Search},
-		{k.Quit},
+		{k.Upload, k.Search, k.List, k.Next, k.Prev, k.Retry, k.Clear, k.Longer, k.Shorter, k.OCR, k.Tmpl, k.Reparse, k.Theme, k.Stats, k.About, k.Help, k.Quit},
+		{k.Batch, k.Lookup, k.Preview, k.Raw, k.Text, k.Cancel, k.Export, k.CSV, k.Copy, k.CopyAll, k.Share, k.Errors, k.Reopen, k.Folder, k.Detail},
+		{k.Enter, k.Field, k.Open, k.Delete, k.Pin, k.Pinned, k.PgNext, k.PgPrev, k.Narrow, k.Widen, k.Navigate, k.Jump, k.Cell, k.Focus},
 	}
 }
 
@@ -60,185 +148,949 @@ type tab int
 const (
 	tabUpload tab = iota
 	tabSearch
+	tabList
+
+	// tabCount must stay last; tab/shift+tab cycle modulo it.
+	tabCount
 )
 
 type model struct {
 	activeTab tab
 	status    string
-	output    string
+	output    string // indented JSON of the last result, for the raw view
+	result    PurchaseOrder
 	spinner   spinner.Model
+	progress  progress.Model
 	table     table.Model
 	help      help.Model
-	loading   bool
+	inFlight  int // running operations; see begin/end
+	rawView   viewport.Model
+	showRaw   bool
+	textView  viewport.Model // the parser's _raw_text, for spotting extraction mistakes
+	showText  bool
+
+	itemsTable table.Model // the result's line items; enter on table opens it
+	showItems  bool
+
+	template string // the -template file, which ctrl+t turns on and off
+
+	errorDetail string
+	errorView   viewport.Model
+	showError   bool
+
+	parseProgress float64
+	progressSeen  bool
+	parseTimeout  time.Duration // for the next parse; + and - adjust it
+	parseLimit    time.Duration // the running parse's timeout
+	parseDeadline time.Time     // zero when no parse is running
+	cancelParse   context.CancelFunc
+	parser        parserOptions
+	recordCount   int
+	cached        bool          // the last result came from the parse cache
+	schema        *resultSchema // nil when checking is off
+	formats       fieldFormats  // how table values are displayed
+	suspect       []string      // how the last result fell short of schema
+	fieldWidth    int           // Field column width; Value gets the rest
+	showDetail    bool          // show the selected cell in full; see cells.go
+	detailCol     int           // selected cell's column in the selected row
+
+	poFormat    *poFormat // nil unless a PO pattern is configured
+	recent      []string  // recently parsed PDFs, newest first
+	stats       parseStats
+	showStats   bool
+	showAbout   bool
+	about       string // aboutText, built when the overlay opens
+	recentTable table.Model
 
-	searchInput textinput.Model
+	quitArmedAt time.Time
+
+	notices []notice // ephemeral messages over status; see status.go
+
+	styles   styles
+	themeIdx int
+	noColor  bool
+
+	searchInput  textinput.Model
 	searchResult string
+	searchTable  table.Model
+	searchField  searchField
+	matchTerm    string   // query the search table's rows matched, highlighted in them
+	vendors      []string // known vendors, loaded on the first vendor search
+	vendorsAsked bool
+	vendorPick   int // highlighted vendor suggestion, -1 for none
+	lastQuery    string
+	history      []string
+	historyPos   int
+	historyDraft string
 	pdfPath      string
+	uploadPath   string
+	lastParsed   string
+	lastPage     int // page the parser found the PO on, 0 if unknown
+	dbPath       string
+	db           *sql.DB
 	width        int
 	height       int
+
+	listTable   table.Model
+	listPage    int
+	listTotal   int
+	listLoading bool
+	listPinned  bool // show favorites only
+
+	batchTable    table.Model
+	batchFiles    []string
+	batchCtx      context.Context
+	batchNext     int
+	batchInFlight int
+	batchDone     int
+	batchFailed   int
+	showBatch     bool
+	workers       int
+	// batchPending holds, by row, the files a batch left unsaved until the
+	// user answers their prompt; batchReview is the row whose prompt is open.
+	batchPending   map[int]tea.Msg
+	batchReview    int
+	batchReviewing bool
+
+	confirmExport *pendingExport
+	confirmDelete *poRecord
+	confirmOpen   *pendingOpen
+	// confirmOverwrite holds a re-parse whose PO is stored with different
+	// values, shown in diffTable until the user picks a side.
+	confirmOverwrite *poConflictMsg
+	confirmDuplicate *duplicateMsg // the parsed PDF matches one already on record
+	diffTable        table.Model
+	confirmOpens     bool // ask before launching a viewer; see startOpen
+	lastFailure      *failedOp
+	preview          bool   // parse without saving to purchase_orders
+	readonly         bool   // -readonly: preview is always on and writes are refused
+	parsePreview     bool   // preview as it was when the running parse started
+	listNote         string // shown ahead of the count after the list reloads
+
+	pathInput    textinput.Model
+	enteringPath bool
+	enteringDir  bool
+	noDialog     bool
+
+	passwordInput    textinput.Model
+	enteringPassword bool
+	password         string // entered for passwordPath; never logged
+	passwordPath     string
 }
 
+// Init parses the PDF given on the command line, if any.
 func (m model) Init() tea.Cmd {
-	return nil
+	if m.uploadPath == "" {
+		return nil
+	}
+	path := m.uploadPath
+	return tea.Batch(func() tea.Msg { return fileSelectedMsg(path) }, m.spinner.Tick)
 }
 
-func initialModel() model {
-	columns := []table.Column{
-		{Title: "Field", Width: 15},
-		{Title: "Value", Width: 30},
-	}
-	t := table.New(table.WithColumns(columns))
-	t.SetStyles(table.DefaultStyles())
+func initialModel(cfg config, db *sql.DB) model {
+	history := loadHistory()
 
-	sp := spinner.New()
-	sp.Style = styleBase.Foreground(colorAccent)
+	// The tables get their styles from applyTheme, below.
+	t := table.New(table.WithColumns(fieldColumns(0, defaultFieldWidth)), table.WithFocused(true))
+
+	frames, ok := spinners[cfg.spinner]
+	if !ok {
+		frames = spinners[defaultSpinner]
+	}
+	sp := spinner.New(spinner.WithSpinner(frames))
 
 	si := textinput.New()
 	si.Placeholder = "Enter PO number..."
 	si.Focus()
-	si.CharLimit = 20
+	si.CharLimit = 100
 	si.Width = 30
 
-	return model{
-		activeTab: tabUpload,
-		status:    "Press 'u' to upload a PDF...",
-		spinner:   sp,
-		help:      help.New(),
-		table:     t,
+	st := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "PO", Width: 15},
+			{Title: "PDF", Width: 40},
+		}),
+		table.WithHeight(maxCandidates),
+		table.WithFocused(true),
+	)
+
+	it := table.New(table.WithColumns(itemsColumns(0)), table.WithFocused(true))
+
+	recent := loadRecent()
+	rt := table.New(
+		table.WithColumns(recentColumns(0)),
+		table.WithRows(recentRows(recent)),
+		table.WithHeight(maxRecent),
+		table.WithFocused(true),
+	)
+
+	lt := table.New(table.WithHeight(15), table.WithFocused(true))
+
+	dt := table.New(table.WithColumns(diffColumns(0)))
+
+	pi := textinput.New()
+	pi.Placeholder = "/path/to/file.pdf"
+	pi.Width = 50
+
+	m := model{
+		activeTab:   tabUpload,
+		status:      tabPrompts[tabUpload],
+		spinner:     sp,
+		progress:    progress.New(progress.WithSolidFill(string(themes[0].Accent))),
+		help:        help.New(),
+		table:       t,
+		itemsTable:  it,
+		rawView:     viewport.New(0, 0),
+		textView:    viewport.New(0, 0),
+		errorView:   viewport.New(0, 0),
 		searchInput: si,
+		searchTable: st,
+		listTable:   lt,
+		batchTable:  newBatchTable(),
+		history:     history,
+		historyPos:  len(history),
+		vendorPick:  -1,
+		pathInput:   pi,
+
+		passwordInput: newPasswordInput(),
+		dbPath:        cfg.databaseLabel(),
+		db:            db,
+
+		recent:       recent,
+		recentTable:  rt,
+		diffTable:    dt,
+		fieldWidth:   defaultFieldWidth,
+		detailCol:    1, // the Value or PDF column
+		poFormat:     cfg.poFormat,
+		confirmOpens: cfg.confirmOpen,
+		parseTimeout: cfg.parseTimeout,
+		parser:       cfg.parserOptions(),
+		schema:       cfg.schema,
+		formats:      cfg.formats,
+		workers:      cfg.workers,
+		readonly:     cfg.readonly,
+		preview:      cfg.readonly,
+		template:     cfg.template,
 	}
+	m.noColor = colorDisabled()
+	m.applyTheme(themeIndex(cfg.theme))
+	return m
 }
 
+// quitConfirmWindow is how long a first q during a parse stays armed.
+const quitConfirmWindow = 3 * time.Second
+
 // ----- Msg Types -----
 type fileSelectedMsg string
 
+// parseResultMsg carries the decoded parser output in PO and the indented
+// JSON in Raw for the raw view. Elapsed is the parser's wall time; Cached
+// means the parser didn't run at all.
 type parseResultMsg struct {
-	Output string
-	Err    error
+	PO      PurchaseOrder
+	Raw     string
+	Err     error
+	Elapsed time.Duration
+	Cached  bool
 }
 
 type searchResultMsg struct {
-	Result string
-	PDF    string
-	Err    error
+	Result  string
+	PDF     string
+	Matches []poMatch
+	Err     error
 }
 
-func openFileDialog() tea.Msg {
-	cmd := exec.Command("zenity", "--file-selection", "--file-filter=PDF files (pdf) | *.pdf")
-	out, err := cmd.Output()
+// checkPDFHeader verifies the file starts with the %PDF- magic bytes.
+func checkPDFHeader(filePath string) error {
+	f, err := os.Open(filePath)
 	if err != nil {
-		return fileSelectedMsg("")
-	}
-	return fileSelectedMsg(strings.TrimSpace(string(out)))
-}
-
-func runPythonParser(filePath string) tea.Cmd {
-	return func() tea.Msg {
-		cmd := exec.Command("python3", "parse_cli.py", filePath)
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			return parseResultMsg{"", fmt.Errorf("Python error: %v\nOutput: %s", err, string(out))}
-		}
-		var jsonObj map[string]interface{}
-		err = json.Unmarshal(out, &jsonObj)
-		if err != nil {
-			return parseResultMsg{"", fmt.Errorf("JSON parse error: %v\nOutput: %s", err, string(out))}
-		}
-		formatted, _ := json.MarshalIndent(jsonObj, "", "  ")
-		return parseResultMsg{string(formatted), nil}
+		return fmt.Errorf("open error: %v", err)
 	}
-}
+	defer f.Close()
 
-func searchDatabase(po string) tea.Cmd {
-	return func() tea.Msg {
-		db, err := sql.Open("sqlite3", "warehouse.db")
-		if err != nil {
-			return searchResultMsg{"", "", fmt.Errorf("DB open error: %v", err)}
-		}
-		defer db.Close()
-
-		var pdfPath string
-		err = db.QueryRow("SELECT pdf_path FROM purchase_orders WHERE po_number = ?", po).Scan(&pdfPath)
-		if err == sql.ErrNoRows {
-			return searchResultMsg{"PO not found.", "", nil}
-		} else if err != nil {
-			return searchResultMsg{"", "", fmt.Errorf("DB query error: %v", err)}
-		}
-		return searchResultMsg{fmt.Sprintf("PDF found: %s", pdfPath), pdfPath, nil}
+	header := make([]byte, 8)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("read error: %v", err)
 	}
-}
-
-func openPDF(pdfPath string) tea.Cmd {
-	return func() tea.Msg {
-		exec.Command("xdg-open", pdfPath).Start()
-		return nil
+	header = header[:n]
+	if !bytes.HasPrefix(header, []byte("%PDF-")) {
+		return fmt.Errorf("missing %%PDF- header in %s\nFirst bytes: %q", filePath, header)
 	}
+	return nil
 }
 
 // ----- Update -----
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.enteringPath {
+			return m.updatePathInput(msg)
+		}
+		if m.enteringPassword {
+			return m.updatePasswordInput(msg)
+		}
+		if m.confirmExport != nil {
+			return m.updateConfirmExport(msg)
+		}
+		if m.confirmDelete != nil {
+			return m.updateConfirmDelete(msg)
+		}
+		if m.confirmOpen != nil {
+			return m.updateConfirmOpen(msg)
+		}
+		if m.confirmOverwrite != nil {
+			return m.updateConfirmOverwrite(msg)
+		}
+		if m.confirmDuplicate != nil {
+			return m.updateConfirmDuplicate(msg)
+		}
+		if m.typingSearch(msg) {
+			break // straight to the input, below
+		}
 		switch {
 		case key.Matches(msg, keys.Quit):
+			if m.loading() && time.Since(m.quitArmedAt) > quitConfirmWindow {
+				m.quitArmedAt = time.Now()
+				if m.cancelParse != nil {
+					m.status = "Parse in progress — press q again to force quit."
+				} else {
+					m.status = "Still working — press q again to force quit."
+				}
+				return m, nil
+			}
+			if m.cancelParse != nil {
+				m.cancelParse()
+			}
 			return m, tea.Quit
-		case key.Matches(msg, keys.Upload):
+		case key.Matches(msg, keys.Upload) || key.Matches(msg, keys.Batch):
+			if m.loading() {
+				m.status = "Busy. Wait for the current job or press esc to cancel."
+				return m, nil
+			}
+			dir := key.Matches(msg, keys.Batch)
 			m.activeTab = tabUpload
+			if m.noDialog {
+				return m.startPathInput(dir)
+			}
+			if dir {
+				m.status = "Opening folder picker..."
+				m.begin()
+				return m, tea.Batch(openDirDialog, m.spinner.Tick)
+			}
 			m.status = "Opening file picker..."
-			m.loading = true
+			m.begin()
 			return m, tea.Batch(openFileDialog, m.spinner.Tick)
+		case key.Matches(msg, keys.Cancel) && m.cancelParse != nil:
+			m.cancelParse()
+			m.status = "Canceling parse..."
+			return m, nil
+		case msg.String() == "esc" && m.activeTab == tabSearch && m.searchInput.Focused():
+			m.browseResults()
+			return m, nil
+		case msg.String() == "/" && m.activeTab == tabSearch:
+			cmd := m.editSearch()
+			return m, cmd
+		case key.Matches(msg, keys.Next) || key.Matches(msg, keys.Prev):
+			// Only switches the view; unlike u/l it never starts a job.
+			step := tab(1)
+			if key.Matches(msg, keys.Prev) {
+				step = tabCount - 1
+			}
+			m.activeTab = (m.activeTab + step) % tabCount
+			return m, nil
+		case key.Matches(msg, keys.Preview) && m.activeTab == tabUpload:
+			if m.refuseReadOnly() {
+				return m, nil
+			}
+			// Takes effect from the next upload; a running parse keeps its mode.
+			m.preview = !m.preview
+			if m.preview {
+				m.status = "Preview mode: parses won't be saved."
+			} else {
+				m.status = "Save mode: parsed POs are saved to the database."
+			}
+			return m, nil
+		case key.Matches(msg, keys.Retry):
+			return m.retry()
+		case key.Matches(msg, keys.Clear):
+			if m.loading() {
+				m.status = "Busy. Wait for the current job or press esc to cancel."
+				return m, nil
+			}
+			m.clearTab()
+			return m, nil
+		case key.Matches(msg, keys.Detail):
+			m.showDetail = !m.showDetail
+			return m, nil
+		case key.Matches(msg, keys.Cell) && m.showDetail && m.detailTable() != nil:
+			m.moveDetailCell(msg.String() == "right")
+			return m, nil
+		case key.Matches(msg, keys.Longer) || key.Matches(msg, keys.Shorter):
+			cmd := m.adjustTimeout(key.Matches(msg, keys.Longer))
+			return m, cmd
+		case key.Matches(msg, keys.OCR):
+			cmd := m.cycleOCR()
+			return m, cmd
+		case key.Matches(msg, keys.Tmpl):
+			cmd := m.toggleTemplate()
+			return m, cmd
+		case key.Matches(msg, keys.Reparse):
+			return m.reparse()
+		case key.Matches(msg, keys.Help):
+			m.help.ShowAll = !m.help.ShowAll
+			return m, nil
+		case key.Matches(msg, keys.Stats):
+			m.showStats = !m.showStats
+			return m, nil
+		case key.Matches(msg, keys.About):
+			m.showAbout = !m.showAbout
+			m.about = aboutText(m.dbPath, m.parser)
+			return m, nil
+		case key.Matches(msg, keys.Theme):
+			m.applyTheme((m.themeIdx + 1) % len(themes))
+			cmd := m.notify("Theme: "+themes[m.themeIdx].Name, noticeTTL)
+			return m, tea.Batch(cmd, saveTheme(themes[m.themeIdx].Name))
+		case key.Matches(msg, keys.Reopen) && m.activeTab == tabUpload:
+			if m.lastParsed == "" {
+				m.status = "No recent PDF."
+				return m, nil
+			}
+			cmd := m.startOpen(m.lastParsed, m.lastPage)
+			return m, cmd
+		case key.Matches(msg, keys.Folder):
+			path := m.folderPath()
+			if path == "" {
+				m.status = "No PDF yet. Parse or search for one first."
+				return m, nil
+			}
+			return m, tea.Batch(m.notify("Opening folder...", noticeTTL), openFolder(path))
+		case key.Matches(msg, keys.Errors) && m.activeTab == tabUpload:
+			if m.errorDetail == "" && !m.showError {
+				m.status = "No errors."
+				return m, nil
+			}
+			m.showError = !m.showError
+			return m, nil
+		case key.Matches(msg, keys.Raw) && m.activeTab == tabUpload && m.output != "":
+			// Each view keeps its own scroll position and selected row.
+			m.showRaw = !m.showRaw
+			m.showText = false
+			if m.showRaw {
+				m.status = "Raw JSON. Press r for the table."
+			} else {
+				m.status = "Parsed fields. Press r for the raw JSON."
+			}
+			return m, nil
+		case key.Matches(msg, keys.Text) && m.activeTab == tabUpload && m.output != "":
+			if m.result.RawText == "" {
+				m.status = "The parser returned no text for this PDF."
+				return m, nil
+			}
+			m.showText = !m.showText
+			m.showRaw = false
+			return m, nil
+		case (key.Matches(msg, keys.Narrow) || key.Matches(msg, keys.Widen)) && m.activeTab == tabUpload:
+			step := 2
+			if key.Matches(msg, keys.Narrow) {
+				step = -step
+			}
+			m.fieldWidth += step
+			m.table.SetColumns(fieldColumns(m.width-8, m.fieldWidth))
+			m.fieldWidth = m.table.Columns()[0].Width
+			return m, nil
+		case key.Matches(msg, keys.Export) && m.activeTab == tabUpload:
+			if m.refuseReadOnly() {
+				return m, nil
+			}
+			if !m.hasResult() {
+				m.status = "Nothing to export."
+				return m, nil
+			}
+			return m.runExport(exportPath(m.lastParsed, ".json"), false)
+		case key.Matches(msg, keys.CSV) && m.activeTab == tabUpload:
+			if m.refuseReadOnly() {
+				return m, nil
+			}
+			if !m.hasResult() {
+				m.status = "Nothing to export."
+				return m, nil
+			}
+			return m.runExport(exportPath(m.lastParsed, ".csv"), true)
+		case key.Matches(msg, keys.Copy) && m.activeTab == tabUpload:
+			row := m.table.SelectedRow()
+			if !m.hasResult() || row == nil {
+				m.status = "Nothing to copy."
+				return m, nil
+			}
+			return m, copyToClipboard(row[1], "Copied to clipboard.")
+		case key.Matches(msg, keys.CopyAll) && m.activeTab == tabUpload:
+			if !m.hasResult() {
+				m.status = "Nothing to copy. Parse a PDF first."
+				return m, nil
+			}
+			return m, copyResultJSON(m.result)
+		case key.Matches(msg, keys.Share):
+			cmd := m.copyParseCommand()
+			return m, cmd
+		case key.Matches(msg, keys.Copy) && m.activeTab == tabSearch:
+			path := m.pdfPath
+			if m.hasCandidates() {
+				path = m.searchTable.SelectedRow()[1]
+			}
+			if path == "" {
+				m.status = "No PDF path to copy. Search for a PO first."
+				return m, nil
+			}
+			return m, copyToClipboard(path, "Path copied: "+path)
+		case key.Matches(msg, keys.Jump) && m.jumpTable() != nil:
+			jumpRows(m.jumpTable(), msg.String())
+			return m, nil
+		case (msg.String() == "up" || msg.String() == "down") && m.activeTab == tabUpload && m.showError:
+			var cmd tea.Cmd
+			m.errorView, cmd = m.errorView.Update(msg)
+			return m, cmd
+		case (msg.String() == "up" || msg.String() == "down") && m.activeTab == tabUpload && m.showBatch:
+			var cmd tea.Cmd
+			m.batchTable, cmd = m.batchTable.Update(msg)
+			return m, cmd
+		case (msg.String() == "up" || msg.String() == "down") && m.showingRecent():
+			var cmd tea.Cmd
+			m.recentTable, cmd = m.recentTable.Update(msg)
+			return m, cmd
+		case msg.String() == "enter" && m.showingRecent():
+			return m.pickRecent()
+		case msg.String() == "enter" && m.activeTab == tabUpload && m.showBatch && !m.loading():
+			return m.reviewBatchRow()
+		case msg.String() == "enter" && m.activeTab == tabUpload && m.hasResult() && !m.loading() && !m.showItems && !m.showRaw && !m.showText && !m.showError && !m.showBatch:
+			m.openItems()
+			return m, nil
+		case msg.String() == "esc" && m.activeTab == tabUpload && m.showItems:
+			m.showItems = false
+			m.status = "Parsed fields."
+			return m, nil
+		case (msg.String() == "up" || msg.String() == "down") && m.activeTab == tabUpload && m.showItems && !m.showRaw && !m.showText:
+			var cmd tea.Cmd
+			m.itemsTable, cmd = m.itemsTable.Update(msg)
+			return m, cmd
+		case (msg.String() == "up" || msg.String() == "down") && m.activeTab == tabUpload && !m.showRaw && !m.showText:
+			var cmd tea.Cmd
+			m.table, cmd = m.table.Update(msg)
+			return m, cmd
+		case key.Matches(msg, keys.List):
+			m.activeTab = tabList
+			m.status = "Loading purchase orders..."
+			m.listPage = 0
+			m.listTable.SetCursor(0)
+			cmd := m.loadList(0)
+			return m, cmd
+		case (key.Matches(msg, keys.PgNext) || key.Matches(msg, keys.PgPrev)) && m.activeTab == tabList && !m.listLoading:
+			page := m.listPage + 1
+			if key.Matches(msg, keys.PgPrev) {
+				page = m.listPage - 1
+			}
+			if page < 0 || page >= pageCount(m.listTotal) {
+				return m, nil
+			}
+			cmd := m.loadList(page)
+			return m, cmd
+		case (msg.String() == "up" || msg.String() == "down") && m.activeTab == tabList:
+			var cmd tea.Cmd
+			m.listTable, cmd = m.listTable.Update(msg)
+			return m, cmd
+		case key.Matches(msg, keys.Delete) && m.activeTab == tabList && !m.listLoading:
+			if m.refuseReadOnly() {
+				return m, nil
+			}
+			row := m.listTable.SelectedRow()
+			if row == nil {
+				m.status = "Nothing to delete."
+				return m, nil
+			}
+			m.confirmDelete = &poRecord{PO: row[0], PDF: row[1]}
+			m.status = "Delete PO " + row[0] + "? (y/f/n)"
+			return m, nil
+		case key.Matches(msg, keys.Pin) && (m.activeTab == tabList || m.activeTab == tabSearch):
+			if m.refuseReadOnly() {
+				return m, nil
+			}
+			po := m.favoritePO()
+			if po == "" {
+				m.status = "Nothing to pin."
+				return m, nil
+			}
+			return m, toggleFavorite(m.db, po)
+		case key.Matches(msg, keys.Pinned):
+			m.activeTab = tabList
+			m.listPinned = !m.listPinned
+			m.status = "Loading purchase orders..."
+			m.listPage = 0
+			m.listTable.SetCursor(0)
+			cmd := m.loadList(0)
+			return m, cmd
+		case msg.String() == "enter" && m.activeTab == tabList:
+			row := m.listTable.SelectedRow()
+			if row == nil {
+				return m, nil
+			}
+			cmd := m.startOpen(row[1], 0)
+			return m, cmd
 		case key.Matches(msg, keys.Search):
 			m.activeTab = tabSearch
-			m.status = "Search active. Type PO and press Enter."
+			cmd := m.editSearch()
+			return m, cmd
+		case key.Matches(msg, keys.Field) && m.activeTab == tabSearch:
+			m.searchField = (m.searchField + 1) % searchField(len(searchFieldNames))
+			m.applySearchValidation()
+			m.status = "Searching " + m.searchField.String() + "."
+			m.vendorPick = -1
+			if m.searchField == fieldVendor && !m.vendorsAsked {
+				m.vendorsAsked = true
+				return m, loadVendors(m.db)
+			}
+			return m, nil
+		case (msg.String() == "up" || msg.String() == "down") && len(m.vendorSuggestions()) > 0 && (msg.String() == "down" || m.vendorPick >= 0):
+			// Up from the top suggestion returns to the input; up from there
+			// still recalls history.
+			m.pickVendor(msg.String() == "down")
+			return m, nil
+		case msg.String() == "enter" && m.vendorPick >= 0 && m.vendorPick < len(m.vendorSuggestions()):
+			vendor := m.vendorSuggestions()[m.vendorPick]
+			m.vendorPick = -1
+			m.searchInput.SetValue(vendor)
+			m.searchInput.CursorEnd()
+			cmd := m.startSearch(vendor)
+			return m, cmd
+		case msg.String() == "enter" && m.activeTab == tabSearch && m.hasCandidates() && !m.searchInput.Focused():
+			row := m.searchTable.SelectedRow()
+			m.pdfPath = row[1]
+			cmd := m.startOpen(m.pdfPath, 0)
+			return m, cmd
+		case (msg.String() == "up" || msg.String() == "down") && m.activeTab == tabSearch && m.hasCandidates() && !m.searchInput.Focused():
+			var cmd tea.Cmd
+			m.searchTable, cmd = m.searchTable.Update(msg)
+			return m, cmd
+		case (msg.String() == "up" || msg.String() == "down") && m.activeTab == tabSearch && m.searchInput.Focused():
+			m.recallHistory(msg.String() == "up")
+			return m, nil
+		case msg.String() == "enter" && m.activeTab == tabSearch && m.searchInput.Err != nil:
+			// A malformed number can't match anything; say why instead of searching.
+			m.status = "Invalid PO number: " + m.searchInput.Err.Error() + "."
 			return m, nil
 		case msg.String() == "enter" && m.activeTab == tabSearch:
-			po := m.searchInput.Value()
-			m.status = "Searching database..."
-			m.loading = true
-			return m, tea.Batch(searchDatabase(po), m.spinner.Tick)
+			cmd := m.startSearch(m.searchInput.Value())
+			return m, cmd
+		case key.Matches(msg, keys.Lookup) && m.activeTab == tabUpload:
+			if !m.hasResult() {
+				m.status = "Nothing parsed yet."
+				return m, nil
+			}
+			po := m.result.Number()
+			if po == "" {
+				m.status = "No PO number was extracted from this PDF, so there's nothing to search for."
+				return m, nil
+			}
+			m.activeTab = tabSearch
+			m.searchField = fieldPO
+			m.applySearchValidation()
+			m.searchInput.SetValue(po)
+			cmd := m.startSearch(po)
+			return m, cmd
 		case msg.String() == "o" && m.activeTab == tabSearch && m.pdfPath != "":
-			m.status = "Opening PDF..."
-			return m, openPDF(m.pdfPath)
+			cmd := m.startOpen(m.pdfPath, 0)
+			return m, cmd
 		}
+	case tea.MouseMsg:
+		return m.updateMouse(msg)
+	case dialogUnavailableMsg:
+		m.end()
+		m.noDialog = true
+		m.activeTab = tabUpload
+		return m.startPathInput(msg.dir)
+	case dirSelectedMsg:
+		if msg == "" {
+			m.status = "Batch canceled."
+			m.end()
+			return m, nil
+		}
+		debugLog.Printf("folder selected: %s", msg)
+		return m.startBatch(string(msg))
+	case batchResultMsg:
+		return m.handleBatchResult(msg)
 	case fileSelectedMsg:
+		// An empty path means the picker was closed; end() lets the spinner's
+		// next tick lapse so nothing keeps spinning.
 		if msg == "" {
-			m.status = "No file selected."
-			m.loading = false
+			m.status = "Upload canceled."
+			m.end()
 			return m, nil
 		}
+		if err := checkPDFHeader(string(msg)); err != nil {
+			m.end()
+			m.status = "Not a valid PDF file — press x for details."
+			m.setErrorDetail(err.Error())
+			return m, nil
+		}
+		debugLog.Printf("file selected: %s", msg)
 		m.status = "Parsing file..."
-		return m, runPythonParser(string(msg))
+		m.uploadPath = string(msg)
+		m.parsePreview = m.preview
+		m.showBatch = false
+		m.parseProgress = 0
+		m.progressSeen = false
+		ctx, cancel := context.WithTimeout(context.Background(), m.parseTimeout)
+		m.parseLimit = m.parseTimeout
+		m.parseDeadline = time.Now().Add(m.parseTimeout)
+		m.cancelParse = cancel
+		if m.parser.JSONL {
+			m.recordCount = 0
+			m.table.SetRows(nil)
+		}
+		return m, tea.Batch(runParser(ctx, m.parserFor(m.uploadPath), string(msg)), readFileInfo(string(msg)))
+	case fileInfoMsg:
+		// Ignore info for a file that already finished or was replaced.
+		if msg.Path == m.uploadPath && m.cancelParse != nil && m.recordCount == 0 {
+			m.status = "Parsing file... (" + msg.String() + ")"
+		}
+		return m, nil
+	case parseProgressMsg:
+		m.parseProgress = msg.Percent
+		m.progressSeen = true
+		return m, waitForParseEvent(msg.events)
+	case recordMsg:
+		m.recordCount++
+		rows := append(m.table.Rows(), recordRows(msg.Index, msg.Record, m.formats)...)
+		m.table.SetRows(rows)
+		m.status = fmt.Sprintf("Parsing file... %d record(s) so far.", m.recordCount)
+		return m, waitForParseEvent(msg.events)
 	case parseResultMsg:
-		m.loading = false
+		m.end()
+		m.stats.record(msg.Elapsed, msg.Err)
+		if m.cancelParse != nil {
+			m.cancelParse()
+			m.cancelParse = nil
+		}
+		m.parseDeadline = time.Time{}
+		switch msg.Err {
+		case errParseCanceled:
+			m.status = "Parse canceled."
+			return m, nil
+		case errPasswordRequired, errWrongPassword:
+			return m.startPasswordPrompt(msg.Err == errWrongPassword)
+		case errParseTimeout:
+			m.status = fmt.Sprintf("Parse timed out after %s — press x for details, + then R to retry with longer.", m.parseLimit)
+			m.setErrorDetail(fmt.Sprintf("%s\n\nThe parser was stopped after %s. Press + to raise the timeout (now %s) for slow documents.", msg.Err, m.parseLimit, m.parseTimeout))
+			m.fail(failedOp{kind: retryParse, path: m.uploadPath})
+			return m, nil
+		}
 		if msg.Err != nil {
-			m.status = "Error parsing file."
-			m.output = msg.Err.Error()
+			m.status = "Parse failed — press x for details, R to retry."
+			m.setErrorDetail(msg.Err.Error())
+			m.fail(failedOp{kind: retryParse, path: m.uploadPath})
 			return m, nil
 		}
-		m.status = "Parsing complete."
-		m.output = msg.Output
-		var parsed map[string]interface{}
-		_ = json.Unmarshal([]byte(msg.Output), &parsed)
-		rows := []table.Row{}
-		for k, v := range parsed {
-			rows = append(rows, table.Row{k, fmt.Sprintf("%v", v)})
+		m.succeeded(retryParse)
+		m.setErrorDetail("")
+		m.cached = msg.Cached
+		m.status = "Parsing complete" + m.cachedNote() + "."
+		m.output = msg.Raw
+		m.result = msg.PO
+		m.suspect = nil
+		if !m.parser.JSONL {
+			m.suspect = m.schema.check(msg.Raw)
 		}
-		m.table.SetRows(rows)
+		if len(m.suspect) > 0 {
+			m.setErrorDetail("The result doesn't match the expected schema, so fields may be missing or wrong:\n\n- " + strings.Join(m.suspect, "\n- "))
+		}
+		m.lastParsed = m.uploadPath
+		m.lastPage = msg.PO.Page
+		m.recent = addRecent(m.recent, m.uploadPath)
+		m.recentTable.SetRows(recentRows(m.recent))
+		m.recentTable.GotoTop()
+		// Raw is the decoded result, already indented, so this view shows the
+		// same data as the table.
+		m.rawView.SetContent(msg.Raw)
+		m.rawView.GotoTop()
+		m.showRaw = false // each new result opens on the table
+		m.textView.SetContent(msg.PO.RawText)
+		m.textView.GotoTop()
+		if msg.PO.RawText == "" {
+			m.showText = false
+		}
+		m.table.SetRows(fieldRows(msg.PO, m.formats))
+		m.showItems = false
+		m.table.GotoTop()
+		po := msg.PO.Number()
+		if noFields(m.table.Rows()) {
+			m.status = "Parsing complete" + m.cachedNote() + ", but the parser returned no fields — press r for the raw output."
+			return m, saveRecent(m.recent)
+		}
+		if m.readonly {
+			m.status = "Parsing complete" + m.cachedNote() + ". " + readOnlyNotice + " Nothing saved."
+			return m, saveRecent(m.recent)
+		}
+		if m.parsePreview {
+			m.status = "Preview complete" + m.cachedNote() + ". Nothing saved — press p to switch to save mode."
+			return m, saveRecent(m.recent)
+		}
+		if po == "" {
+			m.status = "Parsing complete" + m.cachedNote() + ". No PO number found, nothing saved."
+			return m, saveRecent(m.recent)
+		}
+		return m, tea.Batch(saveOrDiff(m.db, po, m.uploadPath, msg.PO), saveRecent(m.recent))
+	case poConflictMsg:
+		return m.showConflict(msg)
+	case duplicateMsg:
+		return m.showDuplicate(msg)
+	case linkResultMsg:
+		if msg.Err != nil {
+			m.status = msg.Err.Error()
+			return m, nil
+		}
+		m.status = "Linked PO " + msg.PO + " to " + msg.PDF + "."
+		return m, nil
+	case saveResultMsg:
+		if msg.Err != nil {
+			m.status = "Parsing complete. " + msg.Err.Error() + " Press R to retry."
+			m.fail(failedOp{kind: retrySave, path: msg.PDF, po: msg.PO, order: msg.Order})
+			return m, nil
+		}
+		m.succeeded(retrySave)
+		m.status = fmt.Sprintf("Parsing complete%s. Saved PO %s.", m.cachedNote(), msg.PO)
+		return m, nil
+	case exportResultMsg:
+		if msg.Err != nil {
+			m.status = msg.Err.Error()
+			return m, nil
+		}
+		m.status = "Exported to " + msg.Path
+		return m, nil
+	case csvExportResultMsg:
+		if msg.Err != nil {
+			m.status = msg.Err.Error()
+			return m, nil
+		}
+		m.status = "Exported CSV to " + msg.Path
+		return m, nil
+	case loadAllMsg:
+		m.end()
+		m.listLoading = false
+		if msg.Err != nil {
+			m.status = msg.Err.Error() + " Press R to retry."
+			m.listNote = ""
+			m.listTable.SetRows(nil)
+			m.fail(failedOp{kind: retryList, page: m.listPage})
+			return m, nil
+		}
+		m.succeeded(retryList)
+		m.setListRows(msg)
+		m.status = fmt.Sprintf("%d purchase order(s). Press Enter to open.", msg.Total)
+		if msg.Favorites {
+			m.status = fmt.Sprintf("%d favorite PO(s). Press Enter to open, F to show all.", msg.Total)
+		}
+		if m.listNote != "" {
+			m.status = m.listNote + " " + m.status
+			m.listNote = ""
+		}
+		return m, nil
+	case deleteResultMsg:
+		if msg.Err != nil {
+			m.status = msg.Err.Error()
+		} else if msg.RemovedPDF {
+			m.status = "Deleted PO " + msg.PO + " and its PDF."
+		} else {
+			m.status = "Deleted PO " + msg.PO + "."
+		}
+		if m.activeTab != tabList {
+			return m, nil
+		}
+		// Reload rather than drop the row locally so the list matches the DB
+		// even when the delete half-failed; the outcome stays in the status.
+		m.listNote = m.status
+		cmd := m.loadList(m.listPage)
+		return m, cmd
+	case favoriteResultMsg:
+		return m.handleFavoriteResult(msg)
+	case openPDFResultMsg:
+		if msg.Err != nil {
+			m.status = msg.Err.Error()
+			return m, nil
+		}
+		text := "Opened " + msg.Path
+		if msg.Page > 0 {
+			text += fmt.Sprintf(" at page %d", msg.Page)
+		}
+		cmd := m.notify(text, noticeTTL)
+		return m, cmd
+	case timeoutSavedMsg:
+		if msg.Err != nil {
+			m.status = "Timeout not saved: " + msg.Err.Error()
+		}
+		return m, nil
+	case vendorsMsg:
+		if msg.Err != nil {
+			// Suggestions are a convenience; try again next time.
+			m.vendorsAsked = false
+			m.status = "Vendor list unavailable: " + msg.Err.Error()
+			return m, nil
+		}
+		m.vendors = msg.Vendors
+		return m, nil
+	case clipboardMsg:
+		if msg.Err != nil {
+			m.status = msg.Err.Error()
+			return m, nil
+		}
+		cmd := m.notify(msg.Notice, noticeTTL)
+		return m, cmd
+	case expireNoticesMsg:
+		m.expireNotices(time.Now())
 		return m, nil
 	case searchResultMsg:
-		m.loading = false
+		m.end()
 		if msg.Err != nil {
-			m.status = "Search error."
+			m.status = "Search error. Press R to retry."
 			m.searchResult = msg.Err.Error()
 			m.pdfPath = ""
+			m.searchTable.SetRows(nil)
+			m.matchTerm = ""
+			m.fail(failedOp{kind: retrySearch, query: m.lastQuery, field: m.searchField})
 			return m, nil
 		}
-		m.status = "Search complete. Press 'o' to open PDF."
+		m.succeeded(retrySearch)
 		m.searchResult = msg.Result
 		m.pdfPath = msg.PDF
+		m.matchTerm = m.lastQuery
+		rows := make([]table.Row, 0, len(msg.Matches))
+		withText := len(msg.Matches) > 0 && msg.Matches[0].Snippet != ""
+		for _, match := range msg.Matches {
+			row := table.Row{match.PO, match.PDF}
+			if withText {
+				row = append(row, match.Snippet)
+			}
+			rows = append(rows, row)
+		}
+		columns := []table.Column{{Title: "PO", Width: 15}, {Title: "PDF", Width: 40}}
+		if withText {
+			columns = []table.Column{{Title: "PO", Width: 15}, {Title: "PDF", Width: 25}, {Title: "Text", Width: 50}}
+		}
+		// Clear rows first so the table never renders rows wider than the new columns.
+		m.searchTable.SetRows(nil)
+		m.searchTable.SetColumns(columns)
+		m.searchTable.SetRows(rows)
+		m.searchTable.GotoTop()
+		switch {
+		case len(rows) > 0:
+			m.status = "Pick a PO with up/down and press Enter to open it."
+		case m.pdfPath != "":
+			m.status = "Search complete. Press 'o' to open PDF or 'y' to copy its path."
+		default:
+			m.status = "Search complete."
+		}
 		return m, nil
 	case spinner.TickMsg:
-		if m.loading {
+		if m.loading() {
 			var cmd tea.Cmd
 			m.spinner, cmd = m.spinner.Update(msg)
 			return m, cmd
@@ -246,45 +1098,502 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		// Leave room for the box border and padding plus the title, status and help lines.
+		m.rawView.Width = max(m.width-8, 1)
+		m.rawView.Height = max(m.height-14, 1)
+		m.errorView.Width = m.rawView.Width
+		m.textView.Width = m.rawView.Width
+		m.textView.Height = m.rawView.Height
+		m.errorView.Height = m.rawView.Height
+		m.help.Width = m.rawView.Width
+		m.table.SetColumns(fieldColumns(m.rawView.Width, m.fieldWidth))
+		m.recentTable.SetColumns(recentColumns(m.rawView.Width))
+		m.itemsTable.SetColumns(itemsColumns(m.rawView.Width))
+		m.fieldWidth = m.table.Columns()[0].Width
 	}
 	var cmd tea.Cmd
+	if m.activeTab == tabUpload && m.showError {
+		m.errorView, cmd = m.errorView.Update(msg)
+		return m, cmd
+	}
+	if m.activeTab == tabUpload && m.showRaw {
+		m.rawView, cmd = m.rawView.Update(msg)
+		return m, cmd
+	}
+	if m.activeTab == tabUpload && m.showText {
+		m.textView, cmd = m.textView.Update(msg)
+		return m, cmd
+	}
+	prev, pos := m.searchInput.Value(), m.searchInput.Position()
 	m.searchInput, cmd = m.searchInput.Update(msg)
+	if m.searchInput.Value() != prev {
+		m.vendorPick = -1
+	}
+	if m.searchInput.Validate != nil && !m.poFormat.allowsAll(m.searchInput.Value()) {
+		m.searchInput.SetValue(prev)
+		m.searchInput.SetCursor(pos)
+		m.status = "That character can't appear in a PO number (" + m.poFormat.pattern + ")."
+	}
+	return m, cmd
+}
+
+// begin counts one more operation in flight; pair every call with end when
+// its result arrives. Callers also batch m.spinner.Tick, which is safe to
+// repeat because the spinner drops ticks from superseded chains.
+func (m *model) begin() {
+	m.inFlight++
+}
+
+func (m *model) end() {
+	if m.inFlight > 0 {
+		m.inFlight--
+	}
+}
+
+// loadList fetches a page of the list tab as its own in-flight operation, so
+// paging works while a parse or batch keeps running.
+func (m *model) loadList(page int) tea.Cmd {
+	m.begin()
+	m.listLoading = true
+	return tea.Batch(loadPOPage(m.db, page, m.listPinned), m.spinner.Tick)
+}
+
+// loading reports whether anything is still running. The spinner keeps
+// ticking only while it is true, so it stops exactly when the last
+// overlapping operation finishes.
+func (m model) loading() bool {
+	return m.inFlight > 0
+}
+
+// setErrorDetail keeps the full text of the last failure out of the main view
+// until the user asks for it; an empty string clears it.
+func (m *model) setErrorDetail(detail string) {
+	m.errorDetail = detail
+	m.errorView.SetContent(detail)
+	m.errorView.GotoTop()
+	if detail == "" {
+		m.showError = false
+	}
+}
+
+// startSearch runs query against the current field and records it in the
+// history.
+func (m *model) startSearch(query string) tea.Cmd {
+	m.lastQuery = query
+	m.history = addToHistory(m.history, query)
+	m.historyPos = len(m.history)
+	m.historyDraft = ""
+	m.status = "Searching database..."
+	m.begin()
+	return tea.Batch(searchDatabase(m.db, query, m.searchField), saveHistory(m.history), m.spinner.Tick)
+}
+
+// tabPrompts is the status each tab starts with, and returns to on c.
+var tabPrompts = map[tab]string{
+	tabUpload: "Press 'u' to upload a PDF...",
+	tabSearch: "Search active. Type PO and press Enter.",
+	tabList:   "Press 'l' to list purchase orders.",
+}
+
+// clearTab drops what the active tab is showing. Search history, recent
+// files and settings are left alone.
+func (m *model) clearTab() {
+	switch m.activeTab {
+	case tabUpload:
+		m.output = ""
+		m.result = PurchaseOrder{}
+		m.suspect = nil
+		m.table.SetRows(nil)
+		m.rawView.SetContent("")
+		m.textView.SetContent("")
+		m.showRaw, m.showText, m.showBatch, m.showItems = false, false, false, false
+		m.itemsTable.SetRows(nil)
+		m.recordCount = 0
+		m.setErrorDetail("")
+	case tabSearch:
+		m.searchInput.Reset()
+		m.searchResult = ""
+		m.pdfPath = ""
+		m.searchTable.SetRows(nil)
+		m.lastQuery = ""
+		m.matchTerm = ""
+		m.vendorPick = -1
+		m.historyPos = len(m.history)
+		m.historyDraft = ""
+		m.applySearchValidation()
+		m.searchInput.Focus()
+	case tabList:
+		m.listTable.SetRows(nil)
+		m.listPage, m.listTotal = 0, 0
+		m.listNote = ""
+	}
+	m.status = tabPrompts[m.activeTab]
+}
+
+// cachedNote marks statuses about a result that came from the parse cache.
+func (m model) cachedNote() string {
+	if m.cached {
+		return " (cached)"
+	}
+	return ""
+}
+
+// hasResult reports whether a parse has succeeded and there is data to export.
+func (m model) hasResult() bool {
+	return m.output != ""
+}
+
+// hasCandidates reports whether the search table is showing partial matches
+// for the query still in the input; while browsing them, Enter opens the
+// selection.
+func (m model) hasCandidates() bool {
+	return len(m.searchTable.Rows()) > 0 && m.searchInput.Value() == m.lastQuery
+}
+
+// jumpTable is the table g/G and ctrl+u/d move in, following the same
+// precedence as up/down. On the search tab that's only while browsing the
+// results; the input keeps home, end and ctrl+u.
+func (m *model) jumpTable() *table.Model {
+	switch {
+	case m.activeTab == tabSearch && !m.searchInput.Focused() && m.hasCandidates():
+		return &m.searchTable
+	case m.activeTab == tabUpload && m.showError:
+		return nil
+	case m.activeTab == tabUpload && m.showBatch:
+		return &m.batchTable
+	case m.showingRecent():
+		return &m.recentTable
+	case m.activeTab == tabUpload && m.showItems && !m.showRaw && !m.showText:
+		return &m.itemsTable
+	case m.activeTab == tabUpload && !m.showRaw && !m.showText:
+		return &m.table
+	case m.activeTab == tabList && !m.listLoading:
+		return &m.listTable
+	}
+	return nil
+}
+
+// jumpRows moves t's cursor for one of the Jump keys.
+func jumpRows(t *table.Model, k string) {
+	switch k {
+	case "g", "home":
+		t.GotoTop()
+	case "G", "end":
+		t.GotoBottom()
+	case "ctrl+u":
+		t.MoveUp(max(t.Height()/2, 1))
+	case "ctrl+d":
+		t.MoveDown(max(t.Height()/2, 1))
+	}
+}
+
+// recallHistory steps through previous searches like a shell, keeping
+// whatever was being typed so stepping back down past the newest restores it.
+func (m *model) recallHistory(older bool) {
+	if len(m.history) == 0 {
+		return
+	}
+	if m.historyPos == len(m.history) {
+		m.historyDraft = m.searchInput.Value()
+	}
+	if older && m.historyPos > 0 {
+		m.historyPos--
+	} else if !older && m.historyPos < len(m.history) {
+		m.historyPos++
+	}
+	if m.historyPos == len(m.history) {
+		m.searchInput.SetValue(m.historyDraft)
+	} else {
+		m.searchInput.SetValue(m.history[m.historyPos])
+	}
+	m.searchInput.CursorEnd()
+}
+
+func (m *model) setListRows(msg loadAllMsg) {
+	columns := []table.Column{
+		{Title: "PO", Width: 15},
+		{Title: "PDF", Width: 40},
+	}
+	if msg.HasDate {
+		columns = append(columns, table.Column{Title: "Date", Width: 20})
+	}
+	columns = append(columns, table.Column{Title: favoriteMark, Width: 1})
+	rows := make([]table.Row, 0, len(msg.Records))
+	for _, r := range msg.Records {
+		row := table.Row{r.PO, r.PDF}
+		if msg.HasDate {
+			row = append(row, r.Date)
+		}
+		mark := ""
+		if r.Favorite {
+			mark = favoriteMark
+		}
+		rows = append(rows, append(row, mark))
+	}
+	// Keep the cursor on the same line across pages; SetRows clamps it when
+	// the new page is shorter.
+	cursor := m.listTable.Cursor()
+	// Clear rows first so the table never renders rows wider than the new columns.
+	m.listTable.SetRows(nil)
+	m.listTable.SetColumns(columns)
+	m.listTable.SetRows(rows)
+	m.listTable.SetCursor(cursor)
+	m.listPage = msg.Page
+	m.listTotal = msg.Total
+}
+
+// updateConfirmDelete answers the delete prompt: y deletes the row, f also
+// removes the PDF; the file is kept unless asked.
+func (m model) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rec := *m.confirmDelete
+	var removePDF bool
+	switch msg.String() {
+	case "y", "Y":
+	case "f", "F":
+		removePDF = true
+	case "n", "N", "esc":
+		m.confirmDelete = nil
+		m.status = "Delete canceled."
+		return m, nil
+	default:
+		return m, nil
+	}
+	m.confirmDelete = nil
+	m.status = "Deleting PO " + rec.PO + "..."
+	return m, deletePO(m.db, rec.PO, rec.PDF, removePDF)
+}
+
+// startPathInput opens the manual path prompt used when no file picker
+// exists, asking for a folder instead of a PDF when dir is set.
+func (m model) startPathInput(dir bool) (tea.Model, tea.Cmd) {
+	m.enteringPath = true
+	m.enteringDir = dir
+	m.pathInput.SetValue("")
+	m.pathInput.Focus()
+	if dir {
+		m.status = "No folder picker found. Type a folder path and press Enter (esc to cancel)."
+	} else {
+		m.status = "No file picker found. Type a PDF path and press Enter (esc to cancel)."
+	}
+	return m, textinput.Blink
+}
+
+// updatePathInput handles keys while the manual path prompt is active.
+func (m model) updatePathInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.enteringPath = false
+		m.pathInput.Blur()
+		m.status = "Upload canceled."
+		if m.enteringDir {
+			m.status = "Batch canceled."
+		}
+		return m, nil
+	case "enter":
+		path := cleanPath(m.pathInput.Value())
+		if path != "" {
+			path = absPath(path)
+		}
+		validate := validatePDFPath
+		if m.enteringDir {
+			validate = validateDirPath
+		}
+		if err := validate(path); err != nil {
+			m.status = err.Error()
+			return m, nil
+		}
+		m.enteringPath = false
+		m.pathInput.Blur()
+		m.begin()
+		if m.enteringDir {
+			return m, tea.Batch(func() tea.Msg { return dirSelectedMsg(path) }, m.spinner.Tick)
+		}
+		return m, tea.Batch(func() tea.Msg { return fileSelectedMsg(path) }, m.spinner.Tick)
+	}
+	var cmd tea.Cmd
+	m.pathInput, cmd = m.pathInput.Update(msg)
 	return m, cmd
 }
 
 // ----- View -----
+
+// Below this size the box and its contents can't be laid out sensibly.
+const (
+	minWidth  = 40
+	minHeight = 16
+)
+
 func (m model) View() string {
-	tabTitle := "[ Upload Tab ]"
-	if m.activeTab == tabSearch {
-		tabTitle = "[ Search Tab ]"
+	if m.width < minWidth || m.height < minHeight {
+		return fmt.Sprintf("Terminal too small (need at least %dx%d, have %dx%d).", minWidth, minHeight, m.width, m.height)
+	}
+
+	title := "PDF PARSER TERMINAL UI"
+	if m.readonly {
+		title += " — READ-ONLY"
 	}
-	top := styleTitle.Width(m.width).Render("PDF PARSER TERMINAL UI") + "\n" + styleTitle.Width(m.width).Render(tabTitle) + "\n\n"
-	status := styleCenterText.Width(m.width).Render("Status: " + m.status)
+	top := m.styles.Title.Width(m.width).Render(title) + "\n" + m.styles.Title.Width(m.width).Render(m.tabBar()) + "\n\n"
+	status := m.styles.CenterText.Width(m.width).Render("Status: " + m.statusLine())
 	content := ""
 
-	if m.activeTab == tabUpload {
-		if m.loading {
-			content = styleCenterText.Width(m.width).Render(m.spinner.View() + " Parsing...")
-		} else if m.output != "" {
+	if m.help.ShowAll {
+		// The full key list replaces the tab content so the box never grows
+		// past the terminal; MaxHeight trims it on very short screens.
+		full := m.help.FullHelpView(keys.FullHelp()) + "\n\n" + "Press ? to close."
+		content = m.styles.CenterText.Width(m.width).MaxHeight(max(m.height-14, 1)).Render(full)
+	} else if m.showStats {
+		// Pad every line to the same width so centering keeps the numbers lined up.
+		block := "Parse stats\n\n" + m.stats.String() + "\n\nPress i to close."
+		content = m.styles.CenterText.Width(m.width).Render(m.styles.Base.Width(lipgloss.Width(block)).Render(block))
+	} else if m.showAbout {
+		block := m.about + "\n\nPress v to close."
+		content = m.styles.CenterText.Width(m.width).Render(m.styles.Base.Width(lipgloss.Width(block)).Render(block))
+	} else if m.confirmExport != nil {
+		content = m.styles.CenterText.Width(m.width).Render(m.confirmExport.path + " already exists.\n\n[y] overwrite   [n] save as new file   [esc] cancel")
+	} else if m.confirmDelete != nil {
+		content = m.styles.CenterText.Width(m.width).Render("Delete PO " + m.confirmDelete.PO + "?\n" + m.confirmDelete.PDF + "\n\n[y] delete, keep PDF   [f] delete and remove PDF   [n] cancel")
+	} else if m.confirmOverwrite != nil {
+		legend := "+ only in new   - only in stored   ~ changed"
+		prompt := "[t] take new   [k] keep stored   [esc] cancel"
+		content = m.styles.CenterText.Width(m.width).Render("PO "+m.confirmOverwrite.PO+" is already on file.") + "\n\n" +
+			m.diffTable.View() + "\n\n" + m.styles.CenterText.Width(m.width).Render(legend+"\n\n"+prompt)
+	} else if m.confirmDuplicate != nil {
+		d := m.confirmDuplicate
+		content = m.styles.CenterText.Width(m.width).Render("This PDF is identical to one already on file:\n" +
+			"PO " + d.ExistingPO + " at " + d.ExistingPDF + "\n\n[l] link PO " + d.ExistingPO + " to " + d.PDF + "   [s] skip")
+	} else if m.confirmOpen != nil {
+		content = m.styles.CenterText.Width(m.width).Render("Open this PDF?\n" + m.confirmOpen.path + "\n\nCommand: " + m.confirmOpen.command + "\n\n[y] open   [n] cancel")
+	} else if m.activeTab == tabUpload {
+		if m.enteringPassword {
+			content = m.styles.CenterText.Width(m.width).Render("Password for "+filepath.Base(m.uploadPath)+":") + "\n" + m.passwordInput.View()
+		} else if m.enteringPath {
+			label := "PDF path:"
+			if m.enteringDir {
+				label = "Folder path:"
+			}
+			content = m.styles.CenterText.Width(m.width).Render(label) + "\n" + m.pathInput.View()
+		} else if m.showError {
+			content = m.errorView.View()
+		} else if m.showBatch {
+			content = m.batchTable.View()
+		} else if m.loading() && m.recordCount > 0 {
 			content = m.table.View()
+		} else if m.loading() && m.progressSeen {
+			content = m.loadingOverlay(m.resultView(), m.progress.ViewAs(m.parseProgress)+" Parsing..."+m.timeLeft())
+		} else if m.loading() {
+			content = m.loadingOverlay(m.resultView(), m.spinner.View()+" Parsing..."+m.timeLeft())
+		} else if m.output != "" {
+			content = m.resultView()
+		} else if m.showingRecent() {
+			content = m.recentTable.View() + "\n" + m.styles.CenterText.Width(m.width).Render("Enter to parse again, u for a new file.")
 		} else {
-			content = styleCenterText.Width(m.width).Render("No output yet.")
+			content = m.styles.CenterText.Width(m.width).Render("No output yet.")
+		}
+		if note := m.extractionNote(); note != "" && !m.enteringPath && !m.enteringPassword {
+			content = m.styles.CenterText.Width(m.width).Render(note) + "\n" + content
 		}
 	} else if m.activeTab == tabSearch {
-		content = styleCenterText.Width(m.width).Render("Search PO:") + "\n" + m.searchInput.View() + "\n\n" + styleCenterText.Width(m.width).Render(m.searchResult)
+		hint := ""
+		if m.searchInput.Err != nil {
+			hint = "\n" + m.styles.CenterText.Width(m.width).Render("Hint: "+m.searchInput.Err.Error())
+		}
+		content = m.styles.CenterText.Width(m.width).Render("Search ("+m.searchField.String()+"):") + "\n" + m.searchInput.View() + hint + m.searchFocusHint() + m.vendorDropdown() + "\n\n" + m.styles.CenterText.Width(m.width).Render(m.searchResult)
+		if len(m.searchTable.Rows()) > 0 {
+			content += "\n" + highlightTable(m.searchTable.View(), m.matchTerm, m.styles.Highlight)
+			if m.hasCandidates() {
+				content = m.withDetail(content, m.searchTable)
+			}
+		}
+	} else if m.activeTab == tabList {
+		page := fmt.Sprintf("Page %d of %d", m.listPage+1, pageCount(m.listTotal))
+		if m.listLoading && len(m.listTable.Rows()) > 0 {
+			content = m.loadingOverlay(m.listTable.View()+"\n"+m.styles.CenterText.Width(m.width).Render(page), m.spinner.View()+" Loading...")
+		} else if m.listLoading {
+			content = m.loadingOverlay("", m.spinner.View()+" Loading...")
+		} else if len(m.listTable.Rows()) > 0 {
+			content = m.withDetail(m.listTable.View()+"\n"+m.styles.CenterText.Width(m.width).Render(page), m.listTable)
+		} else if m.listPinned {
+			content = m.styles.CenterText.Width(m.width).Render("No favorite POs. Press f on a PO to pin it, F to show all.")
+		} else {
+			content = m.styles.CenterText.Width(m.width).Render("No purchase orders.")
+		}
 	}
 
-	footer := styleCenterText.Width(m.width).Render(m.help.View(keys))
-	box := styleBox.Width(m.width - 4).Height(m.height - 4).Render(top + content + "\n\n" + status + "\n\n" + footer)
+	footer := m.styles.CenterText.Width(m.width).Render(m.help.ShortHelpView(keys.ShortHelp()))
+	box := m.styles.Box.Width(m.width - 4).Height(m.height - 4).Render(top + content + "\n\n" + status + "\n\n" + footer)
 	return box
 }
 
 func main() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if err := p.Start(); err != nil {
+	if code, ok := dispatchSubcommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: pdf-parser [flags] [file.pdf]")
+		flag.PrintDefaults()
+	}
+	showVersion := flag.Bool("version", false, "print the version and resolved paths, then exit")
+	cfg, err := loadConfig(flag.CommandLine, os.Args[1:])
+	if err != nil {
 		fmt.Println("Error:", err)
 		os.Exit(1)
 	}
-}
+	// -version ignores any file argument, valid or not.
+	if *showVersion {
+		fmt.Println(aboutText(cfg.databaseLabel(), cfg.parserOptions()))
+		return
+	}
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	// A PDF given at launch (often dragged into the terminal) is parsed as
+	// soon as the TUI starts, skipping the picker.
+	startPath := ""
+	if flag.NArg() == 1 {
+		startPath = absPath(cleanPath(flag.Arg(0)))
+		if err := validatePDFPath(startPath); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+	if cfg.clearCache {
+		if err := clearParseCache(); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+	if cfg.logPath != "" {
+		f, err := openLog(cfg.logPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		debugLog.Printf("session start: db=%s script=%s python=%s", cfg.databaseLabel(), cfg.scriptPath, cfg.pythonPath)
+	}
 
+	driver, source := cfg.dataSource()
+	db, err := openDatabase(driver, source, cfg.readonly)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
 
+	if cfg.readonly {
+		keys.markReadOnly()
+	}
+	m := initialModel(cfg, db)
+	if startPath != "" {
+		m.uploadPath = startPath
+		m.begin()
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	_, err = p.Run()
+	db.Close()
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// testModel is a sized model whose history, recents and theme live in a
+// temporary config directory.
+func testModel(t *testing.T) model {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	m := initialModel(config{parseTimeout: time.Minute}, nil)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	return next.(model)
+}
+
+// send runs msg through Update, as bubbletea would, without running the
+// returned command.
+func send(t *testing.T, m model, msg tea.Msg) (model, tea.Cmd) {
+	t.Helper()
+	next, cmd := m.Update(msg)
+	return next.(model), cmd
+}
+
+// press sends a key: a name like "enter" or "esc", or text to type.
+func press(t *testing.T, m model, k string) (model, tea.Cmd) {
+	t.Helper()
+	switch k {
+	case "enter":
+		return send(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	case "esc":
+		return send(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	}
+	return send(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+}
+
+func TestLoadingClearsOnlyWhenAllWorkEnds(t *testing.T) {
+	m := testModel(t)
+	m, _ = press(t, m, "u") // opens the file picker: one operation
+	m.activeTab = tabSearch
+	m, _ = press(t, m, "PO1")
+	m, _ = press(t, m, "enter") // a search overlaps it
+	if m.inFlight != 2 {
+		t.Fatalf("inFlight = %d, want 2", m.inFlight)
+	}
+
+	m, _ = send(t, m, searchResultMsg{Result: "PO not found."})
+	if !m.loading() {
+		t.Fatal("loading cleared while the upload was still open")
+	}
+	if _, cmd := send(t, m, m.spinner.Tick()); cmd == nil {
+		t.Error("spinner stopped ticking while still loading")
+	}
+
+	m, _ = send(t, m, parseResultMsg{PurchaseOrder{}, "", errParseCanceled, 0, false})
+	if m.loading() {
+		t.Fatalf("still loading with inFlight = %d", m.inFlight)
+	}
+	if _, cmd := send(t, m, m.spinner.Tick()); cmd != nil {
+		t.Error("spinner kept ticking after the last operation ended")
+	}
+}
+
+func TestCanceledPickerStopsSpinner(t *testing.T) {
+	m := testModel(t)
+	m, cmd := press(t, m, "u")
+	if !m.loading() || cmd == nil {
+		t.Fatal("u didn't start the picker")
+	}
+	m, _ = send(t, m, fileSelectedMsg(""))
+	if m.status != "Upload canceled." {
+		t.Errorf("status = %q", m.status)
+	}
+	if m.loading() {
+		t.Error("still loading after the picker closed")
+	}
+	if _, cmd := send(t, m, m.spinner.Tick()); cmd != nil {
+		t.Error("spinner still ticking")
+	}
+}
+
+func TestEmptyResultShowsNoFields(t *testing.T) {
+	for name, script := range map[string]string{
+		"whitespace only": `printf '  \n\t\n'`,
+		"empty object":    `echo '{}'`,
+		"blank values":    `echo '{"po_number": "", "items": [], "notes": {}}'`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := parseWith(t, execParser(t, script))
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			m := testModel(t)
+			m.begin()
+			m, _ = send(t, m, result)
+			if !strings.Contains(m.status, "the parser returned no fields") {
+				t.Errorf("status = %q", m.status)
+			}
+			if !strings.Contains(m.View(), "Parser returned no fields.") {
+				t.Error("view doesn't say there were no fields")
+			}
+		})
+	}
+}
+
+func TestPromptsShowOnUploadTab(t *testing.T) {
+	m := testModel(t)
+	m.uploadPath = "/tmp/locked.pdf"
+	m.begin()
+	m.activeTab = tabList
+	m, _ = send(t, m, parseResultMsg{PurchaseOrder{}, "", errPasswordRequired, 0, false})
+	if m.activeTab != tabUpload || !strings.Contains(m.View(), "Password for locked.pdf:") {
+		t.Errorf("password prompt not shown: tab %d", m.activeTab)
+	}
+
+	m = testModel(t)
+	m.begin()
+	m.activeTab = tabSearch
+	m, _ = send(t, m, dialogUnavailableMsg{})
+	if m.activeTab != tabUpload || !strings.Contains(m.View(), "PDF path:") {
+		t.Errorf("path prompt not shown: tab %d", m.activeTab)
+	}
+}
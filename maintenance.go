@@ -0,0 +1,57 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ----- Maintenance -----
+
+// vacuumResult reports what vacuumDatabase did.
+type vacuumResult struct {
+	Backup string
+	Before int64
+	After  int64
+}
+
+// backupPath names a timestamped copy next to the database, e.g.
+// warehouse.db -> warehouse-20261014-150405.db.
+func backupPath(dbPath string, now time.Time) string {
+	ext := filepath.Ext(dbPath)
+	return strings.TrimSuffix(dbPath, ext) + "-" + now.Format("20060102-150405") + ext
+}
+
+// vacuumDatabase writes a compacted backup with VACUUM INTO, then vacuums the
+// database itself. Both take SQLite's locks, so they wait for (or, through
+// retryBusy, outlast) writers in other processes instead of copying a
+// half-written file.
+func vacuumDatabase(db *sql.DB, dbPath string) (vacuumResult, error) {
+	res := vacuumResult{Backup: backupPath(dbPath, time.Now())}
+	if !activeDialect.vacuum {
+		return res, fmt.Errorf("vacuum only works on SQLite databases, not %s", activeDialect.name)
+	}
+	if info, err := os.Stat(dbPath); err == nil {
+		res.Before = info.Size()
+	}
+	if _, err := os.Stat(res.Backup); err == nil {
+		return res, fmt.Errorf("backup error: %s already exists", res.Backup)
+	}
+	err := retryBusy(db, func() error {
+		_, err := db.Exec("VACUUM INTO ?", res.Backup)
+		return err
+	})
+	if err != nil {
+		return res, fmt.Errorf("backup error: %v", err)
+	}
+	if err := retryBusy(db, func() error { _, err := db.Exec("VACUUM"); return err }); err != nil {
+		return res, fmt.Errorf("vacuum error: %v", err)
+	}
+	if info, err := os.Stat(dbPath); err == nil {
+		res.After = info.Size()
+	}
+	return res, nil
+}
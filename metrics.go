@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ----- Parse Metrics -----
+
+// parseStats counts this session's parses, single and batch alike. Canceled
+// parses and ones stopped to ask for a password aren't counted either way.
+type parseStats struct {
+	succeeded int
+	failed    int
+	total     time.Duration // summed over every counted parse
+	last      time.Duration
+	slowest   time.Duration
+}
+
+func (s *parseStats) record(elapsed time.Duration, err error) {
+	if err == errParseCanceled || err == errPasswordRequired || err == errWrongPassword {
+		return
+	}
+	if err != nil {
+		s.failed++
+	} else {
+		s.succeeded++
+	}
+	s.total += elapsed
+	s.last = elapsed
+	s.slowest = max(s.slowest, elapsed)
+}
+
+func (s parseStats) count() int { return s.succeeded + s.failed }
+
+// String lays the numbers out for the stats overlay.
+func (s parseStats) String() string {
+	if s.count() == 0 {
+		return "No parses yet this session."
+	}
+	round := func(d time.Duration) string { return d.Round(10 * time.Millisecond).String() }
+	lines := []string{
+		fmt.Sprintf("Parses:    %d", s.count()),
+		fmt.Sprintf("Succeeded: %d", s.succeeded),
+		fmt.Sprintf("Failed:    %d", s.failed),
+		"",
+		fmt.Sprintf("Last:      %s", round(s.last)),
+		fmt.Sprintf("Average:   %s", round(s.total/time.Duration(s.count()))),
+		fmt.Sprintf("Slowest:   %s", round(s.slowest)),
+	}
+	return strings.Join(lines, "\n")
+}
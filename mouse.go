@@ -0,0 +1,149 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+)
+
+// ----- Mouse -----
+
+// tabLabel is the name shown for t in the tab bar.
+func (m model) tabLabel(t tab) string {
+	switch t {
+	case tabSearch:
+		return "Search Tab"
+	case tabList:
+		return "List Tab"
+	}
+	if m.preview && !m.readonly {
+		return "Upload Tab — PREVIEW"
+	}
+	return "Upload Tab"
+}
+
+// tabBar lists every tab, bracketing the active one, so tabs can be clicked.
+func (m model) tabBar() string {
+	labels := make([]string, tabCount)
+	for t := tab(0); t < tabCount; t++ {
+		labels[t] = m.tabLabel(t)
+		if t == m.activeTab {
+			labels[t] = "[ " + labels[t] + " ]"
+		}
+	}
+	return strings.Join(labels, "   ")
+}
+
+// updateMouse gives the mouse the same reach as the keyboard: the wheel acts
+// as ↑/↓ and a click picks a tab or a table row.
+func (m model) updateMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.enteringPath || m.enteringPassword || m.confirmExport != nil || m.confirmDelete != nil ||
+		m.confirmOpen != nil || m.confirmOverwrite != nil || m.confirmDuplicate != nil {
+		return m, nil
+	}
+	if tea.MouseEvent(msg).IsWheel() && m.activeTab == tabSearch && m.hasCandidates() && m.searchInput.Focused() {
+		// The wheel scrolls the results, not the search history.
+		m.browseResults()
+	}
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		return m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	case msg.Button == tea.MouseButtonWheelDown:
+		return m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	case msg.Button != tea.MouseButtonLeft || msg.Action != tea.MouseActionPress:
+		return m, nil
+	}
+
+	// Hit-test against what's on screen rather than re-deriving the layout.
+	lines := strings.Split(ansi.Strip(m.View()), "\n")
+	if msg.Y < 0 || msg.Y >= len(lines) {
+		return m, nil
+	}
+	line := []rune(lines[msg.Y])
+	for t := tab(0); t < tabCount; t++ {
+		if start := runeIndex(line, m.tabLabel(t)); start >= 0 && strings.Contains(string(line), m.tabLabel((t+1)%tabCount)) {
+			if msg.X >= start && msg.X < start+len([]rune(m.tabLabel(t))) {
+				// Like tab/shift+tab, a click only switches the view.
+				m.activeTab = t
+				return m, nil
+			}
+		}
+	}
+	if t := m.clickableTable(); t != nil {
+		if i := clickedRow(t, string(line)); i >= 0 {
+			t.SetCursor(i)
+			if t == &m.searchTable && m.searchInput.Focused() {
+				m.browseResults()
+			}
+		}
+	}
+	return m, nil
+}
+
+// clickableTable is the table on screen whose cursor a click moves, if any.
+func (m *model) clickableTable() *table.Model {
+	switch {
+	case m.help.ShowAll || m.showStats:
+		return nil
+	case m.activeTab == tabSearch && len(m.searchTable.Rows()) > 0:
+		return &m.searchTable
+	case m.activeTab == tabList && !m.listLoading:
+		return &m.listTable
+	case m.activeTab != tabUpload || m.showError || m.showRaw || m.showText:
+		return nil
+	case m.showBatch:
+		return &m.batchTable
+	case m.showingRecent():
+		return &m.recentTable
+	case m.output != "" || m.recordCount > 0:
+		return &m.table
+	}
+	return nil
+}
+
+// clickedRow finds the row rendered on line, preferring the one nearest the
+// cursor when values repeat, or returns -1.
+func clickedRow(t *table.Model, line string) int {
+	best := -1
+	for i, row := range t.Rows() {
+		if !strings.Contains(line, renderRowText(t.Columns(), row)) {
+			continue
+		}
+		if best < 0 || abs(i-t.Cursor()) < abs(best-t.Cursor()) {
+			best = i
+		}
+	}
+	return best
+}
+
+// renderRowText mirrors how table.DefaultStyles lays a row out, minus color.
+func renderRowText(cols []table.Column, row table.Row) string {
+	var b strings.Builder
+	for i, col := range cols {
+		value := ""
+		if i < len(row) {
+			value = row[i]
+		}
+		cell := runewidth.Truncate(value, col.Width, "…")
+		b.WriteString(" " + runewidth.FillRight(cell, col.Width) + " ")
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+func runeIndex(line []rune, s string) int {
+	i := strings.Index(string(line), s)
+	if i < 0 {
+		return -1
+	}
+	return len([]rune(string(line)[:i]))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
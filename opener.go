@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- External Viewer -----
+
+// openPDFResultMsg reports an opened PDF. Page is the page the viewer was
+// asked to show, or 0 when it opened at the start.
+type openPDFResultMsg struct {
+	Path string
+	Page int
+	Err  error
+}
+
+// pageViewer is a PDF viewer that can be told which page to open at.
+type pageViewer struct {
+	name string
+	args func(path string, page int) []string
+}
+
+// pageViewers are tried in order when a page is requested. The default
+// openers (xdg-open, open, rundll32) can't target a page, so without one of
+// these the PDF opens at page 1.
+var pageViewers = map[string][]pageViewer{
+	"linux": {
+		{"zathura", func(p string, n int) []string { return []string{"-P", strconv.Itoa(n), p} }},
+		{"okular", func(p string, n int) []string { return []string{"-p", strconv.Itoa(n), p} }},
+		{"evince", func(p string, n int) []string { return []string{"-i", strconv.Itoa(n), p} }},
+		{"mupdf", func(p string, n int) []string { return []string{p, strconv.Itoa(n)} }},
+	},
+	"windows": {
+		{"SumatraPDF", func(p string, n int) []string { return []string{"-page", strconv.Itoa(n), p} }},
+	},
+}
+
+// pageCommand returns a viewer command that opens path at page, or nil.
+func pageCommand(path string, page int) *exec.Cmd {
+	for _, v := range pageViewers[runtime.GOOS] {
+		if bin, err := exec.LookPath(v.name); err == nil {
+			return exec.Command(bin, v.args(path, page)...)
+		}
+	}
+	return nil
+}
+
+// openerCommand returns the platform's "open with default app" command.
+func openerCommand(path string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", path)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	default:
+		return exec.Command("xdg-open", path)
+	}
+}
+
+// viewerCommand picks the command openPDF will run, along with the page it
+// opens at (0 when the viewer can't target one).
+func viewerCommand(pdfPath string, page int) (*exec.Cmd, int) {
+	if page > 1 {
+		if cmd := pageCommand(pdfPath, page); cmd != nil {
+			return cmd, page
+		}
+	}
+	return openerCommand(pdfPath), 0
+}
+
+// openPDF opens pdfPath in a viewer, at page when page > 1 and a viewer that
+// supports it is installed.
+func openPDF(pdfPath string, page int) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := os.Stat(pdfPath); err != nil {
+			return openPDFResultMsg{pdfPath, 0, fmt.Errorf("File not found: %s", pdfPath)}
+		}
+		cmd, page := viewerCommand(pdfPath, page)
+		debugLog.Printf("open pdf: %s page %d via %s", pdfPath, page, cmd.Path)
+		if err := cmd.Start(); err != nil {
+			return openPDFResultMsg{pdfPath, 0, fmt.Errorf("Could not run %s: %v", cmd.Path, err)}
+		}
+		// Reap the opener in the background so it doesn't linger as a zombie.
+		go cmd.Wait()
+		return openPDFResultMsg{pdfPath, page, nil}
+	}
+}
+
+// folderCommand returns the platform's command for showing dir in the file
+// manager.
+func folderCommand(dir string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("explorer", dir)
+	}
+	return openerCommand(dir)
+}
+
+// openFolder opens the directory holding pdfPath. The result reuses
+// openPDFResultMsg with the folder as Path.
+func openFolder(pdfPath string) tea.Cmd {
+	return func() tea.Msg {
+		dir := filepath.Dir(pdfPath)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return openPDFResultMsg{dir, 0, fmt.Errorf("Folder not found: %s", dir)}
+		}
+		cmd := folderCommand(dir)
+		debugLog.Printf("open folder: %s via %s", dir, cmd.Path)
+		if err := cmd.Start(); err != nil {
+			return openPDFResultMsg{dir, 0, fmt.Errorf("Could not run %s: %v", cmd.Path, err)}
+		}
+		go cmd.Wait()
+		return openPDFResultMsg{dir, 0, nil}
+	}
+}
+
+// folderPath is the PDF whose folder P opens: the search tab's match or
+// selected partial match, the selected list row, or the last parsed PDF.
+func (m model) folderPath() string {
+	switch m.activeTab {
+	case tabSearch:
+		if m.hasCandidates() {
+			return m.searchTable.SelectedRow()[1]
+		}
+		if m.pdfPath != "" {
+			return m.pdfPath
+		}
+	case tabList:
+		if row := m.listTable.SelectedRow(); row != nil && !m.listLoading {
+			return row[1]
+		}
+	}
+	return m.lastParsed
+}
+
+// pendingOpen is an open waiting on the user when -confirm-open is set.
+type pendingOpen struct {
+	path    string
+	page    int
+	command string // what will run, for display
+}
+
+// startOpen runs openPDF behind a short-lived "Opening PDF..." notice, or
+// with confirmOpens set, first shows what will run and waits for y.
+func (m *model) startOpen(pdfPath string, page int) tea.Cmd {
+	if m.confirmOpens {
+		cmd, _ := viewerCommand(pdfPath, page)
+		m.confirmOpen = &pendingOpen{pdfPath, page, strings.Join(cmd.Args, " ")}
+		m.status = "Open this PDF? (y/n)"
+		return nil
+	}
+	return tea.Batch(m.notify("Opening PDF...", noticeTTL), openPDF(pdfPath, page))
+}
+
+// updateConfirmOpen handles the answer to the confirm-open prompt.
+func (m model) updateConfirmOpen(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	pending := *m.confirmOpen
+	switch msg.String() {
+	case "y", "Y", "enter":
+		m.confirmOpen = nil
+		return m, tea.Batch(m.notify("Opening PDF...", noticeTTL), openPDF(pending.path, pending.page))
+	case "n", "N", "esc":
+		m.confirmOpen = nil
+		m.status = "Open canceled."
+		return m, nil
+	}
+	return m, nil
+}
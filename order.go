@@ -0,0 +1,187 @@
+package main
+
+import "encoding/json"
+
+// ----- Purchase Order -----
+
+// LineItem is one row of a purchase order's items list. Keys an item has
+// beyond these, or with an unexpected type, are kept in its Extra.
+type LineItem struct {
+	SKU         string  `json:"sku"`
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Total       float64 `json:"total"`
+
+	Extra   map[string]interface{} `json:"-"`
+	present map[string]bool        // keys the parser sent, zero or not
+}
+
+// PurchaseOrder holds the fields the app knows about. Anything else the
+// parser returns, or a known field whose value has an unexpected type, is
+// kept in Extra so nothing is dropped from the table or exports.
+type PurchaseOrder struct {
+	PONumber      string     `json:"po_number"`
+	Vendor        string     `json:"vendor"`
+	InvoiceNumber string     `json:"invoice_number"`
+	Date          string     `json:"date"`
+	Total         float64    `json:"total"`
+	Items         []LineItem `json:"items"`
+	Page          int        `json:"page"` // 1-based page the PO number was found on
+	// RawText is the document text the parser extracted, kept for
+	// full-text search rather than shown as a field. Clearing it drops the
+	// key.
+	RawText string `json:"_raw_text,omitempty"`
+
+	Extra   map[string]interface{} `json:"-"`
+	present map[string]bool        // keys the parser sent, zero or not
+}
+
+// decodePurchaseOrder splits decoded parser output into typed fields and Extra.
+func decodePurchaseOrder(data map[string]interface{}) PurchaseOrder {
+	var po PurchaseOrder
+	items, hasItems := data["items"].([]interface{})
+	if hasItems {
+		// Each item keeps its own extras, so one odd item leaves the rest typed.
+		// An empty list stays a list rather than becoming null.
+		po.Items = make([]LineItem, 0, len(items))
+		for _, v := range items {
+			item, ok := v.(map[string]interface{})
+			if !ok {
+				hasItems = false
+				break
+			}
+			po.Items = append(po.Items, decodeLineItem(item))
+		}
+		if !hasItems {
+			po.Items = nil
+		}
+	}
+	known := map[string]interface{}{
+		"po_number":      &po.PONumber,
+		"vendor":         &po.Vendor,
+		"invoice_number": &po.InvoiceNumber,
+		"date":           &po.Date,
+		"total":          &po.Total,
+		"page":           &po.Page,
+		"_raw_text":      &po.RawText,
+	}
+	rest := data
+	if hasItems {
+		rest = make(map[string]interface{}, len(data))
+		for k, v := range data {
+			if k != "items" {
+				rest[k] = v
+			}
+		}
+	}
+	po.present, po.Extra = decodeFields(rest, known)
+	if hasItems {
+		po.present["items"] = true
+	}
+	return po
+}
+
+func decodeLineItem(data map[string]interface{}) LineItem {
+	var it LineItem
+	it.present, it.Extra = decodeFields(data, map[string]interface{}{
+		"sku":         &it.SKU,
+		"description": &it.Description,
+		"quantity":    &it.Quantity,
+		"unit_price":  &it.UnitPrice,
+		"total":       &it.Total,
+	})
+	return it
+}
+
+// decodeFields decodes each key of data that has a destination in known and
+// returns which ones did, with everything else as extras. A null, or a value
+// of the wrong type, goes to the extras so it's written back as it came.
+func decodeFields(data, known map[string]interface{}) (present map[string]bool, extra map[string]interface{}) {
+	present = make(map[string]bool)
+	for k, v := range data {
+		if dst, ok := known[k]; ok && v != nil {
+			b, _ := json.Marshal(v)
+			if json.Unmarshal(b, dst) == nil {
+				present[k] = true
+				continue
+			}
+		}
+		if extra == nil {
+			extra = make(map[string]interface{})
+		}
+		extra[k] = v
+	}
+	return present, extra
+}
+
+// MarshalJSON writes the typed fields and Extra back out as one object.
+func (po PurchaseOrder) MarshalJSON() ([]byte, error) {
+	type plain PurchaseOrder
+	return mergeFields(plain(po), map[string]bool{
+		"po_number":      po.PONumber == "",
+		"vendor":         po.Vendor == "",
+		"invoice_number": po.InvoiceNumber == "",
+		"date":           po.Date == "",
+		"total":          po.Total == 0,
+		"items":          len(po.Items) == 0,
+		"page":           po.Page == 0,
+	}, po.present, po.Extra)
+}
+
+func (it LineItem) MarshalJSON() ([]byte, error) {
+	type plain LineItem
+	return mergeFields(plain(it), map[string]bool{
+		"sku":         it.SKU == "",
+		"description": it.Description == "",
+		"quantity":    it.Quantity == 0,
+		"unit_price":  it.UnitPrice == 0,
+		"total":       it.Total == 0,
+	}, it.present, it.Extra)
+}
+
+// mergeFields encodes v and drops the keys that are zero (per zero) and
+// weren't in the parser's output, then lays extra over the rest. A known key
+// only lands in extra when its typed decode failed, so the original value
+// there wins.
+func mergeFields(v interface{}, zero, present map[string]bool, extra map[string]interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var merged map[string]interface{}
+	if err := json.Unmarshal(b, &merged); err != nil {
+		return nil, err
+	}
+	for k, isZero := range zero {
+		if isZero && !present[k] {
+			delete(merged, k)
+		}
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return json.Marshal(merged)
+}
+
+// has reports whether the parser sent key for this item, even as zero.
+func (it LineItem) has(key string) bool {
+	return it.present[key]
+}
+
+// Map returns the order as generic JSON values for flattening and CSV export.
+func (po PurchaseOrder) Map() map[string]interface{} {
+	b, _ := json.Marshal(po)
+	var m map[string]interface{}
+	_ = json.Unmarshal(b, &m)
+	return m
+}
+
+// Number is the PO number to save, or "" when the parser reported "UNKNOWN"
+// or found none.
+func (po PurchaseOrder) Number() string {
+	if po.PONumber == "UNKNOWN" {
+		return ""
+	}
+	return po.PONumber
+}
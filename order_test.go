@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func decodeJSON(t *testing.T, s string) PurchaseOrder {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &data); err != nil {
+		t.Fatal(err)
+	}
+	return decodePurchaseOrder(data)
+}
+
+func TestPurchaseOrderKeepsZeroValues(t *testing.T) {
+	po := decodeJSON(t, `{"po_number": "", "date": "", "total": 0, "items": [], "page": 0}`)
+	m := po.Map()
+	for _, k := range []string{"po_number", "date", "total", "items", "page"} {
+		if _, ok := m[k]; !ok {
+			t.Errorf("%s dropped: %v", k, m)
+		}
+	}
+	if items, ok := m["items"].([]interface{}); !ok || len(items) != 0 {
+		t.Errorf("items = %#v, want an empty list", m["items"])
+	}
+	if _, ok := m["vendor"]; ok {
+		t.Errorf("vendor wasn't sent but was written: %v", m)
+	}
+}
+
+func TestLineItemExtrasStayPerItem(t *testing.T) {
+	po := decodeJSON(t, `{"items": [{"sku": "A", "quantity": 0, "color": "red"}, {"sku": "B", "quantity": 2}]}`)
+	if len(po.Items) != 2 || po.Items[0].SKU != "A" || po.Items[1].Quantity != 2 {
+		t.Fatalf("items = %+v", po.Items)
+	}
+	if _, ok := po.Extra["items"]; ok {
+		t.Fatalf("items went to Extra: %v", po.Extra)
+	}
+	if po.Items[0].Extra["color"] != "red" {
+		t.Errorf("item extra lost: %+v", po.Items[0])
+	}
+	b, _ := json.Marshal(po)
+	want := `{"items":[{"color":"red","quantity":0,"sku":"A"},{"quantity":2,"sku":"B"}]}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
+
+func TestPurchaseOrderWrongTypeGoesToExtra(t *testing.T) {
+	po := decodeJSON(t, `{"total": "12.50", "items": "none", "vendor": null}`)
+	m := po.Map()
+	if m["total"] != "12.50" || m["items"] != "none" {
+		t.Errorf("got %v", m)
+	}
+	if v, ok := m["vendor"]; !ok || v != nil {
+		t.Errorf("null vendor not kept: %v", m)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ----- Loading Overlay -----
+
+// loadingOverlay shows busy in a box over a dimmed copy of what the tab
+// showed before, so the last result stays in view while the next one loads.
+// With nothing behind it, busy is shown on its own line as before.
+func (m model) loadingOverlay(behind, busy string) string {
+	if behind == "" {
+		return m.styles.CenterText.Width(m.width).Render(busy)
+	}
+	lines := strings.Split(ansi.Strip(behind), "\n")
+	for i, line := range lines {
+		lines[i] = m.styles.Dim.Render(line)
+	}
+	return placeOver(strings.Join(lines, "\n"), m.styles.Overlay.Render(busy))
+}
+
+// placeOver draws fg centered on top of bg, keeping bg's styling on either
+// side. bg is padded when fg doesn't fit in it.
+func placeOver(bg, fg string) string {
+	bgLines := strings.Split(bg, "\n")
+	fgLines := strings.Split(fg, "\n")
+	width := max(lipgloss.Width(bg), lipgloss.Width(fg))
+	for len(bgLines) < len(fgLines) {
+		bgLines = append(bgLines, "")
+	}
+	top := (len(bgLines) - len(fgLines)) / 2
+	left := (width - lipgloss.Width(fg)) / 2
+	for i, line := range fgLines {
+		row := bgLines[top+i]
+		row += strings.Repeat(" ", max(width-ansi.StringWidth(row), 0))
+		bgLines[top+i] = ansi.Cut(row, 0, left) + line + ansi.TruncateLeft(row, left+ansi.StringWidth(line), "")
+	}
+	return strings.Join(bgLines, "\n")
+}
+
+// resultView is the upload tab's view of the last result, or "" before the
+// first.
+func (m model) resultView() string {
+	switch {
+	case m.output == "":
+		return ""
+	case m.showRaw:
+		return m.rawView.View()
+	case m.showText:
+		return m.textView.View()
+	case m.showItems:
+		return m.withDetail(m.itemsTable.View(), m.itemsTable)
+	case noFields(m.table.Rows()):
+		return m.styles.CenterText.Width(m.width).Render("Parser returned no fields.")
+	case len(m.suspect) > 0:
+		warning := "Suspect result: " + strings.Join(m.suspect, "; ") + " — press x for details."
+		return m.styles.CenterText.Width(m.width).Render(warning) + "\n" + m.withDetail(m.table.View(), m.table)
+	}
+	return m.withDetail(m.table.View(), m.table)
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Python Parser -----
+
+// parseProgressMsg reports a "progress: N%" line from the parser's stderr.
+// It carries the event channel so Update can keep listening for the result.
+type parseProgressMsg struct {
+	Percent float64
+	events  <-chan tea.Msg
+}
+
+var progressLine = regexp.MustCompile(`^progress:\s*(\d+(?:\.\d+)?)%$`)
+
+var (
+	errParseTimeout  = errors.New("parser timed out")
+	errParseCanceled = errors.New("parse canceled")
+
+	errPasswordRequired = errors.New("PDF is password-protected")
+	errWrongPassword    = errors.New("wrong password for this PDF")
+)
+
+// passwordErrors maps the "error_code" the parser prints for encrypted PDFs.
+var passwordErrors = map[string]error{
+	"password_required": errPasswordRequired,
+	"wrong_password":    errWrongPassword,
+}
+
+// passwordError returns the matching error when out is the parser's
+// encrypted-PDF reply, or nil.
+func passwordError(out []byte) error {
+	var reply struct {
+		Code string `json:"error_code"`
+	}
+	if json.Unmarshal(out, &reply) != nil {
+		return nil
+	}
+	return passwordErrors[reply.Code]
+}
+
+// recordMsg delivers one object from a JSON Lines run as soon as the parser
+// prints it, before the rest of the document is done.
+type recordMsg struct {
+	Index  int
+	Record map[string]interface{}
+	events <-chan tea.Msg
+}
+
+// Parser turns a PDF into a purchase order. The UI only sees this, so a
+// backend can be anything from a subprocess to pure Go.
+type Parser interface {
+	Parse(ctx context.Context, path string) (PurchaseOrder, error)
+}
+
+// streamingParser is a Parser that can also report progress and records as
+// it goes. Stream must finish with exactly one parseResultMsg.
+type streamingParser interface {
+	Parser
+	Stream(ctx context.Context, path string, events chan tea.Msg)
+}
+
+// parserBackends are the backends -parser can pick from.
+var parserBackends = map[string]func(parserOptions) Parser{
+	// python runs Script with Python.
+	"python": func(o parserOptions) Parser {
+		return subprocessParser{o, "Python", []string{o.Python, o.Script}, o.Script, "install Python 3 or set PDFPARSER_PYTHON"}
+	},
+	// exec runs Command directly. It gets the same arguments and must follow
+	// the same protocol as the Python script: JSON on stdout, progress lines
+	// and diagnostics on stderr.
+	"exec": func(o parserOptions) Parser {
+		return subprocessParser{o, filepath.Base(o.Command), []string{o.Command}, "", "check -parser-cmd"}
+	},
+}
+
+const defaultBackend = "python"
+
+// parserOptions describes which backend to use and how to invoke it.
+type parserOptions struct {
+	Backend string // a key of parserBackends; "" means python
+	Python  string
+	Script  string
+	Command string // the executable for the exec backend
+	// JSONL asks the script for one JSON object per line (--jsonl) instead of
+	// a single object, and streams them back as recordMsgs.
+	JSONL bool
+	// MaxOutput is the most stdout the parser may print, in bytes; 0 means
+	// no limit. Past it the parser is stopped rather than buffered further.
+	MaxOutput int64
+	// Password unlocks an encrypted PDF. It reaches the parser as
+	// PDFPARSER_PASSWORD, never as an argument, and is never logged.
+	Password string
+	// Cache reuses the result of an earlier parse of the unchanged file; see
+	// cacheKey.
+	Cache bool
+	// OCR is one of ocrModes, or "" for auto; Template is a prompt file for
+	// the script. See extractArgs.
+	OCR      string
+	Template string
+}
+
+// cappedBuffer collects output up to max bytes and calls onFull, once, when
+// a write would pass it. Writes after that fail so the copy stops.
+type cappedBuffer struct {
+	buf    bytes.Buffer // not embedded: its ReadFrom would bypass the cap
+	max    int64
+	full   bool
+	onFull func()
+}
+
+var errOutputTooLarge = errors.New("output too large")
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.full {
+		return 0, errOutputTooLarge
+	}
+	if b.max > 0 && int64(b.buf.Len()+len(p)) > b.max {
+		b.full = true
+		b.onFull()
+		return 0, errOutputTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+// newParser returns the backend opts selects.
+func newParser(opts parserOptions) (Parser, error) {
+	backend := firstNonEmpty(opts.Backend, defaultBackend)
+	newBackend, ok := parserBackends[backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown parser backend %q (want python or exec)", backend)
+	}
+	if backend == "exec" && opts.Command == "" {
+		return nil, errors.New("the exec parser backend needs -parser-cmd")
+	}
+	return newBackend(opts), nil
+}
+
+// runParser runs the selected backend until it finishes or ctx is done; the
+// caller owns ctx and uses it for both the timeout and manual cancel.
+func runParser(ctx context.Context, opts parserOptions, filePath string) tea.Cmd {
+	return func() tea.Msg {
+		events := make(chan tea.Msg)
+		go startParse(ctx, opts, filePath, events)
+		return <-events
+	}
+}
+
+// parseSync runs the parser outside the Bubble Tea loop, discarding progress
+// and streamed records, and returns only the final result.
+func parseSync(ctx context.Context, opts parserOptions, filePath string) parseResultMsg {
+	events := make(chan tea.Msg)
+	go startParse(ctx, opts, filePath, events)
+	for {
+		if result, ok := (<-events).(parseResultMsg); ok {
+			return result
+		}
+	}
+}
+
+// startParse answers from the parse cache when it can, streams from backends
+// that can, and wraps a plain Parse call in a single parseResultMsg for those
+// that can't.
+func startParse(ctx context.Context, opts parserOptions, filePath string, events chan tea.Msg) {
+	if key, ok := cacheKey(opts, filePath); ok {
+		if cached, ok := loadCachedParse(key); ok {
+			debugLog.Printf("parse cache hit: %s", filePath)
+			events <- cached
+			return
+		}
+	}
+	p, err := newParser(opts)
+	if err != nil {
+		events <- parseResultMsg{PurchaseOrder{}, "", err, 0, false}
+		return
+	}
+	if s, ok := p.(streamingParser); ok {
+		s.Stream(ctx, filePath, events)
+		return
+	}
+	start := time.Now()
+	po, err := p.Parse(ctx, filePath)
+	elapsed := time.Since(start)
+	if err != nil {
+		events <- parseResultMsg{PurchaseOrder{}, "", err, elapsed, false}
+		return
+	}
+	formatted, _ := json.MarshalIndent(po, "", "  ")
+	if key, ok := cacheKey(opts, filePath); ok {
+		storeCachedParse(key, string(formatted))
+	}
+	events <- parseResultMsg{po, string(formatted), nil, elapsed, false}
+}
+
+// waitForParseEvent returns the next progress update or the final result.
+func waitForParseEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// subprocessParser runs an external parser: argv plus the file path, with
+// --jsonl before it in JSON Lines mode.
+type subprocessParser struct {
+	opts parserOptions
+	name string // used in error messages, e.g. "Python error: ..."
+	argv []string
+	// script, when set, must exist before anything is run.
+	script string
+	// notFound suggests a fix when argv[0] isn't installed.
+	notFound string
+}
+
+func (p subprocessParser) Parse(ctx context.Context, path string) (PurchaseOrder, error) {
+	events := make(chan tea.Msg)
+	go p.Stream(ctx, path, events)
+	for {
+		if result, ok := (<-events).(parseResultMsg); ok {
+			return result.PO, result.Err
+		}
+	}
+}
+
+// Stream runs the parser, forwarding progress lines from stderr (and records
+// from stdout in JSONL mode) as they arrive and finishing with exactly one
+// parseResultMsg.
+func (p subprocessParser) Stream(ctx context.Context, filePath string, events chan tea.Msg) {
+	opts := p.opts
+	start := time.Now()
+	finish := func(po PurchaseOrder, raw string, err error) {
+		if key, ok := cacheKey(opts, filePath); ok && err == nil {
+			storeCachedParse(key, raw)
+		}
+		events <- parseResultMsg{po, raw, err, time.Since(start), false}
+	}
+	if p.script != "" {
+		if _, err := os.Stat(p.script); err != nil {
+			finish(PurchaseOrder{}, "", fmt.Errorf("parser script not found: %s — pass -script or set PDFPARSER_SCRIPT", p.script))
+			return
+		}
+	}
+	debugLog.Printf("parse start: %s", filePath)
+	defer func() {
+		debugLog.Printf("parse finish: %s (%s)", filePath, time.Since(start).Round(time.Millisecond))
+	}()
+
+	args := append([]string(nil), p.argv[1:]...)
+	if opts.JSONL {
+		args = append(args, "--jsonl")
+	}
+	args = append(args, opts.extractArgs()...)
+	// runCtx also covers stopping the parser when its output hits the cap; the
+	// timeout and cancel checks below still look at ctx.
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	cmd := exec.CommandContext(runCtx, p.argv[0], append(args, filePath)...)
+	if opts.Password != "" {
+		cmd.Env = append(os.Environ(), "PDFPARSER_PASSWORD="+opts.Password)
+	}
+	stdout := &cappedBuffer{max: opts.MaxOutput, onFull: stop}
+	var stdoutPipe io.Reader
+	if opts.JSONL {
+		pipe, err := cmd.StdoutPipe()
+		if err != nil {
+			finish(PurchaseOrder{}, "", fmt.Errorf("%s error: %v", p.name, err))
+			return
+		}
+		stdoutPipe = pipe
+	} else {
+		cmd.Stdout = stdout
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		finish(PurchaseOrder{}, "", fmt.Errorf("%s error: %v", p.name, err))
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("%s not found — %s", p.argv[0], p.notFound)
+		} else {
+			err = fmt.Errorf("%s error: %v", p.name, err)
+		}
+		finish(PurchaseOrder{}, "", err)
+		return
+	}
+
+	var records []interface{}
+	var recordErr error
+	recordsDone := make(chan struct{})
+	if opts.JSONL {
+		go func() {
+			defer close(recordsDone)
+			records, recordErr = readRecords(stdoutPipe, stdout, events)
+		}()
+	} else {
+		close(recordsDone)
+	}
+
+	var diagnostics strings.Builder
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 64*1024), maxStderrLine)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := progressLine.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			percent, _ := strconv.ParseFloat(match[1], 64)
+			events <- parseProgressMsg{min(percent/100, 1), events}
+			continue
+		}
+		diagnostics.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		diagnostics.WriteString("(stderr not read past here: " + err.Error() + ")\n")
+	}
+	// Keep draining so a parser still writing to stderr never blocks on a
+	// full pipe, which would end as a timeout.
+	io.Copy(io.Discard, stderr)
+
+	<-recordsDone
+	err = cmd.Wait()
+	out := stdout.buf.Bytes()
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		finish(PurchaseOrder{}, "", errParseTimeout)
+		return
+	case context.Canceled:
+		finish(PurchaseOrder{}, "", errParseCanceled)
+		return
+	}
+	if stdout.full {
+		debugLog.Printf("parse error: %s: output over %d bytes", filePath, opts.MaxOutput)
+		finish(PurchaseOrder{}, "", fmt.Errorf("parser output exceeded %s — raise -max-output if this PDF is expected to be this large", humanSize(opts.MaxOutput)))
+		return
+	}
+	if err != nil {
+		if perr := passwordError(out); perr != nil {
+			debugLog.Printf("parse error: %s: %v", filePath, perr)
+			finish(PurchaseOrder{}, "", perr)
+			return
+		}
+		debugLog.Printf("parse error: %s: %v", filePath, err)
+		finish(PurchaseOrder{}, "", withStderr(fmt.Errorf("%s error: %s\nOutput: %s", p.name, exitDescription(err), out), diagnostics.String()))
+		return
+	}
+	if opts.JSONL {
+		if recordErr != nil {
+			finish(PurchaseOrder{}, "", withStderr(recordErr, diagnostics.String()))
+			return
+		}
+		data := map[string]interface{}{"records": records}
+		formatted, _ := json.MarshalIndent(data, "", "  ")
+		finish(decodePurchaseOrder(data), string(formatted), nil)
+		return
+	}
+
+	// Blank output is an empty result rather than malformed JSON.
+	jsonObj := map[string]interface{}{}
+	if len(bytes.TrimSpace(out)) == 0 {
+		out = []byte("{}")
+	}
+	if err := json.Unmarshal(out, &jsonObj); err != nil {
+		finish(PurchaseOrder{}, "", withStderr(jsonError(err, out), diagnostics.String()))
+		return
+	}
+	formatted, _ := json.MarshalIndent(jsonObj, "", "  ")
+	finish(decodePurchaseOrder(jsonObj), string(formatted), nil)
+}
+
+// readRecords decodes one JSON object per stdout line, sending each as a
+// recordMsg. Raw output is mirrored into raw for error messages.
+func readRecords(r io.Reader, raw io.Writer, events chan tea.Msg) ([]interface{}, error) {
+	var records []interface{}
+	var firstErr error
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		_, err := raw.Write(scanner.Bytes())
+		if err == nil {
+			_, err = raw.Write([]byte{'\n'})
+		}
+		if err != nil {
+			// Keep draining so the parser isn't left blocked on a full pipe.
+			io.Copy(io.Discard, r)
+			return records, err
+		}
+		if len(text) == 0 {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal(text, &record); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("stdout line %d: %v", line, jsonError(err, text))
+			}
+			continue
+		}
+		events <- recordMsg{len(records), record, events}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil && firstErr == nil {
+		firstErr = fmt.Errorf("read error: %v", err)
+	}
+	return records, firstErr
+}
+
+// withStderr appends the tail of the parser's stderr, minus progress lines,
+// to err. Only stdout is decoded, so warnings there never break a parse, but
+// they often explain one that failed; a traceback ends with the cause.
+func withStderr(err error, stderr string) error {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return err
+	}
+	if lines := strings.Split(stderr, "\n"); len(lines) > stderrTailLines {
+		stderr = "…\n" + strings.Join(lines[len(lines)-stderrTailLines:], "\n")
+	}
+	return fmt.Errorf("%w\nStderr: %s", err, stderr)
+}
+
+// stderrTailLines is how much of the parser's stderr an error keeps.
+const stderrTailLines = 20
+
+// maxStderrLine is the longest stderr line read whole, e.g. a traceback
+// with long reprs.
+const maxStderrLine = 4 * 1024 * 1024
+
+// exitDescription says how the parser process ended: its exit code, or the
+// signal that killed it. Exit code 2 conventionally means bad arguments, as
+// with argparse.
+func exitDescription(err error) string {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err.Error()
+	}
+	switch code := exitErr.ExitCode(); code {
+	case -1:
+		return "the parser was killed (" + exitErr.ProcessState.String() + ")"
+	case 2:
+		return "the parser exited with code 2 (usage error)"
+	default:
+		return fmt.Sprintf("the parser exited with code %d", code)
+	}
+}
+
+// jsonSnippetRadius is how many bytes either side of a JSON error are shown.
+const jsonSnippetRadius = 40
+
+// jsonError describes a decode failure with its line, column and the
+// surrounding output, so a stray log line before the JSON is easy to spot.
+func jsonError(err error, out []byte) error {
+	var offset int64 = -1
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	}
+	if offset < 0 || len(out) == 0 {
+		return fmt.Errorf("JSON parse error: %v\nOutput: %s", err, out)
+	}
+	// Offsets point just past the offending byte; step back onto it.
+	pos := int(offset)
+	if pos > 0 {
+		pos--
+	}
+	if pos > len(out) {
+		pos = len(out)
+	}
+	line := 1 + bytes.Count(out[:pos], []byte("\n"))
+	col := pos - bytes.LastIndexByte(out[:pos], '\n')
+
+	start := max(pos-jsonSnippetRadius, 0)
+	end := min(pos+jsonSnippetRadius, len(out))
+	before := strings.ReplaceAll(string(out[start:pos]), "\n", "⏎")
+	after := strings.ReplaceAll(string(out[pos:end]), "\n", "⏎")
+	return fmt.Errorf("JSON parse error at line %d, column %d (byte %d): %v\nNear: %s\n      %s^\nOutput: %s",
+		line, col, offset, err, before+after, strings.Repeat(" ", utf8.RuneCountInString(before)), out)
+}
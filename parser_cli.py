@@ -1,3 +1,4 @@
+import os
 import sys
 import json
 import re
@@ -79,13 +80,47 @@ prompt = PromptTemplate(input_variables=["raw_text"], template=FEW_SHOT_PROMPT)
 llm = OllamaLLM(model="llama3")
 translator_chain = prompt | llm
 
-def extract_text_from_pdf(pdf_path):
+def report_progress(percent):
+    # The Go TUI reads these lines from stderr; stdout stays pure JSON.
+    print(f"progress: {percent}%", file=sys.stderr, flush=True)
+
+# The TUI passes a password through the environment rather than argv so it
+# never shows up in process listings. It's never printed or logged.
+PASSWORD = os.environ.get("PDFPARSER_PASSWORD") or None
+
+# Exit status for encrypted PDFs; stdout carries an "error_code" of
+# "password_required" or "wrong_password" so the TUI can prompt.
+EXIT_PASSWORD = 3
+
+def open_pdf(pdf_path):
     doc = fitz.open(pdf_path)
+    if doc.needs_pass and not (PASSWORD and doc.authenticate(PASSWORD)):
+        code = "wrong_password" if PASSWORD else "password_required"
+        print(json.dumps({"error": "PDF is password-protected", "error_code": code}))
+        sys.exit(EXIT_PASSWORD)
+    return doc
+
+# OCR is "auto" (only when PyMuPDF finds too little text), "on" or "off";
+# the TUI sets it with --ocr / --no-ocr.
+OCR = "auto"
+
+def needs_ocr(text):
+    if OCR == "auto":
+        return len(text.strip()) <= 100
+    return OCR == "on"
+
+def extract_text_from_pdf(pdf_path):
+    doc = open_pdf(pdf_path)
     fitz_text = "\n".join(page.get_text() for page in doc)
-    if len(fitz_text.strip()) > 100:
+    if not needs_ocr(fitz_text):
+        report_progress(40)
         return fitz_text
-    images = convert_from_path(pdf_path)
-    ocr_text = "\n".join(pytesseract.image_to_string(img) for img in images)
+    images = convert_from_path(pdf_path, userpw=PASSWORD)
+    pages = []
+    for i, img in enumerate(images, start=1):
+        pages.append(pytesseract.image_to_string(img))
+        report_progress(10 + 50 * i // len(images))
+    ocr_text = "\n".join(pages)
     return ocr_text
 
 def clean_text(text):
@@ -94,12 +129,100 @@ def clean_text(text):
     text = re.sub(r'[^a-z0-9#:\-. ]', '', text)
     return re.sub(r' +', ' ', text).strip()
 
+def extract_pages(pdf_path):
+    # Yields (page_number, text) one page at a time, OCRing only the pages
+    # PyMuPDF can't read, so JSON Lines output can start before the end.
+    doc = open_pdf(pdf_path)
+    for i, page in enumerate(doc, start=1):
+        text = page.get_text()
+        if needs_ocr(text):
+            images = convert_from_path(pdf_path, first_page=i, last_page=i, userpw=PASSWORD)
+            text = "\n".join(pytesseract.image_to_string(img) for img in images)
+        yield i, text
+
+def find_po_page(pdf_path, translated_po):
+    # 1-based page whose text holds the PO digits, so the viewer can jump
+    # there; None when it can't be pinned down.
+    if "-" not in translated_po:
+        return None
+    digits = translated_po.split("-", 1)[1]
+    for i, page in enumerate(open_pdf(pdf_path), start=1):
+        if digits in page.get_text():
+            return i
+    return None
+
+def translate_po(cleaned_text):
+    # Returns (translated_po, None) or (None, error_dict).
+    result = translator_chain.invoke({"raw_text": cleaned_text})
+    json_match = re.search(r'\{.*?\}', result, re.DOTALL)
+    if not json_match:
+        return None, {"error": "No JSON", "raw": result}
+    try:
+        po_data = json.loads(json_match.group())
+    except json.JSONDecodeError:
+        return None, {"error": "Bad JSON", "raw": result}
+
+    translated_po = po_data.get("translated_po", "UNKNOWN")
+    store_code = translated_po.split("-")[0] if "-" in translated_po else "UNKNOWN"
+    if store_code not in approved_stores:
+        translated_po = "UNKNOWN"
+    return translated_po, None
+
+def run_jsonl(file_path):
+    # One JSON object per page, flushed as soon as it's ready.
+    report_progress(5)
+    total = len(open_pdf(file_path))
+    for page_no, text in extract_pages(file_path):
+        cleaned_text = clean_text(text)
+        record = {"page": page_no}
+        if not cleaned_text:
+            record["error"] = "No text extracted"
+        else:
+            translated_po, error = translate_po(cleaned_text)
+            if error:
+                record.update(error)
+            else:
+                record["po_number"] = translated_po
+        print(json.dumps(record), flush=True)
+        report_progress(5 + 95 * page_no // max(total, 1))
+
+def parse_args(argv):
+    # Returns (file paths, jsonl). --template swaps the prompt for a file's,
+    # which must use {raw_text} where the document goes.
+    global OCR, translator_chain
+    files, jsonl = [], False
+    args = iter(argv)
+    for a in args:
+        if a == "--jsonl":
+            jsonl = True
+        elif a == "--ocr":
+            OCR = "on"
+        elif a == "--no-ocr":
+            OCR = "off"
+        elif a == "--template":
+            with open(next(args, ""), encoding="utf-8") as f:
+                template = PromptTemplate(input_variables=["raw_text"], template=f.read())
+            translator_chain = template | llm
+        else:
+            files.append(a)
+    return files, jsonl
+
 if __name__ == "__main__":
-    if len(sys.argv) < 2:
+    try:
+        args, jsonl = parse_args(sys.argv[1:])
+    except OSError as e:
+        print(json.dumps({"error": f"Template error: {e}"}))
+        sys.exit(2)
+    if not args:
         print(json.dumps({"error": "No file path provided"}))
         sys.exit(1)
 
-    file_path = sys.argv[1]
+    file_path = args[0]
+    if jsonl:
+        run_jsonl(file_path)
+        sys.exit(0)
+
+    report_progress(5)
     raw_text = extract_text_from_pdf(file_path)
     cleaned_text = clean_text(raw_text)
 
@@ -107,22 +230,18 @@ if __name__ == "__main__":
         print(json.dumps({"error": "No text extracted"}))
         sys.exit(1)
 
-    result = translator_chain.invoke({"raw_text": cleaned_text})
-    json_match = re.search(r'\{.*?\}', result, re.DOTALL)
-    if json_match:
-        try:
-            po_data = json.loads(json_match.group())
-        except json.JSONDecodeError:
-            print(json.dumps({"error": "Bad JSON", "raw": result}))
-            sys.exit(1)
-    else:
-        print(json.dumps({"error": "No JSON", "raw": result}))
+    report_progress(60)
+    translated_po, error = translate_po(cleaned_text)
+    report_progress(90)
+    if error:
+        print(json.dumps(error))
         sys.exit(1)
 
-    translated_po = po_data.get("translated_po", "UNKNOWN")
-    store_code = translated_po.split("-")[0] if "-" in translated_po else "UNKNOWN"
-    if store_code not in approved_stores:
-        translated_po = "UNKNOWN"
-
-    print(json.dumps({"po_number": translated_po}))
-
+    result = {"po_number": translated_po, "_raw_text": raw_text}
+    page = find_po_page(file_path, translated_po)
+    if page:
+        result["page"] = page
+    report_progress(100)
+    # _raw_text feeds the TUI's full-text search and its T (extracted text)
+    # view; it isn't shown as a field.
+    print(json.dumps(result))
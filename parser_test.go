@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// execParser writes script as an exec-backend parser and returns options
+// that run it, with the cache off.
+func execParser(t *testing.T, script string) parserOptions {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "parser.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return parserOptions{Backend: "exec", Command: path, MaxOutput: defaultMaxOutputMB << 20}
+}
+
+func parseWith(t *testing.T, opts parserOptions) parseResultMsg {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return parseSync(ctx, opts, filepath.Join(t.TempDir(), "po.pdf"))
+}
+
+func TestMalformedOutputShowsSnippet(t *testing.T) {
+	result := parseWith(t, execParser(t, `printf 'Loading model...\n{"po_number": "PO-1"}\n'`))
+	if result.Err == nil {
+		t.Fatal("expected a JSON error")
+	}
+	msg := result.Err.Error()
+	for _, want := range []string{"JSON parse error at line 1, column 1 (byte 1)", "Near: Loading model...", "\n      ^\n"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error lacks %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestJSONErrorPointsAtLine(t *testing.T) {
+	out := []byte("{\"po_number\": \"PO-1\",\n  oops}")
+	var v map[string]interface{}
+	err := jsonError(json.Unmarshal(out, &v), out)
+	if msg := err.Error(); !strings.Contains(msg, "at line 2, column 3") || !strings.Contains(msg, `"PO-1",⏎  oops}`) {
+		t.Errorf("got:\n%s", msg)
+	}
+}
+
+func TestFailingParserReportsExitAndStderr(t *testing.T) {
+	result := parseWith(t, execParser(t, `sh -c 'echo x >&2; exit 2'`))
+	if result.Err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"exited with code 2 (usage error)", "Stderr: x"} {
+		if !strings.Contains(result.Err.Error(), want) {
+			t.Errorf("error lacks %q: %v", want, result.Err)
+		}
+	}
+
+	m := testModel(t)
+	m.begin()
+	m, _ = send(t, m, result)
+	if m.status != "Parse failed — press x for details, R to retry." {
+		t.Errorf("status = %q", m.status)
+	}
+	if !strings.Contains(m.errorDetail, "exited with code 2") || !strings.Contains(m.errorDetail, "Stderr: x") {
+		t.Errorf("error detail = %q", m.errorDetail)
+	}
+}
+
+func TestStderrKeepsTail(t *testing.T) {
+	result := parseWith(t, execParser(t, `for i in $(seq 1 30); do echo "line $i" >&2; done; exit 1`))
+	msg := result.Err.Error()
+	if !strings.Contains(msg, "exited with code 1") || !strings.Contains(msg, "Stderr: …\nline 11\n") || strings.Contains(msg, "line 10\n") {
+		t.Errorf("got:\n%s", msg)
+	}
+}
+
+func TestLongStderrLineDoesNotStallParser(t *testing.T) {
+	opts := execParser(t, `head -c 200000 /dev/zero | tr '\0' x >&2; echo >&2; echo '{"po_number": "PO-1"}'`)
+	result := parseWith(t, opts)
+	if result.Err != nil {
+		t.Fatalf("parse failed: %.200v", result.Err)
+	}
+	if result.PO.PONumber != "PO-1" {
+		t.Errorf("PO = %+v", result.PO)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- PDF Passwords -----
+
+func newPasswordInput() textinput.Model {
+	in := textinput.New()
+	in.Placeholder = "password"
+	in.EchoMode = textinput.EchoPassword
+	in.EchoCharacter = '•'
+	in.Width = 30
+	return in
+}
+
+// startPasswordPrompt asks for the password of the PDF that just failed to
+// open. wrong is set when the last password didn't unlock it.
+func (m model) startPasswordPrompt(wrong bool) (tea.Model, tea.Cmd) {
+	// The prompt is only drawn on the upload tab, which may not be the one
+	// showing if the user switched tabs during the parse.
+	m.activeTab = tabUpload
+	m.enteringPassword = true
+	m.passwordInput.Reset()
+	m.passwordInput.Focus()
+	m.status = filepath.Base(m.uploadPath) + " is password-protected. Enter its password (esc to cancel)."
+	if wrong {
+		m.status = "Wrong password. Try again (esc to cancel)."
+	}
+	return m, textinput.Blink
+}
+
+// updatePasswordInput handles keys while the password prompt is active.
+func (m model) updatePasswordInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.enteringPassword = false
+		m.passwordInput.Reset()
+		m.passwordInput.Blur()
+		m.status = "Upload canceled."
+		return m, nil
+	case "enter":
+		if m.passwordInput.Value() == "" {
+			m.status = "Enter the PDF's password, or press esc to cancel."
+			return m, nil
+		}
+		// Kept only in memory, for this file, so R can retry with it.
+		m.password, m.passwordPath = m.passwordInput.Value(), m.uploadPath
+		m.enteringPassword = false
+		m.passwordInput.Reset()
+		m.passwordInput.Blur()
+		m.begin()
+		path := m.uploadPath
+		return m, tea.Batch(func() tea.Msg { return fileSelectedMsg(path) }, m.spinner.Tick)
+	}
+	var cmd tea.Cmd
+	m.passwordInput, cmd = m.passwordInput.Update(msg)
+	return m, cmd
+}
+
+// parserFor returns the parser options for path, with its password if one
+// was entered for it.
+func (m model) parserFor(path string) parserOptions {
+	opts := m.parser
+	if path == m.passwordPath {
+		opts.Password = m.password
+	}
+	return opts
+}
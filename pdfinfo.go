@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- File Info -----
+
+// fileInfoMsg describes the PDF being parsed. Pages is 0 when it couldn't be
+// counted cheaply.
+type fileInfoMsg struct {
+	Path  string
+	Size  int64
+	Pages int
+}
+
+// maxPageScanSize skips the page count for files too big to read in one go.
+const maxPageScanSize = 50 << 20
+
+// pageObject matches "/Type /Page" dictionaries but not the "/Type /Pages"
+// tree nodes.
+var pageObject = regexp.MustCompile(`/Type\s*/Page\b`)
+
+func readFileInfo(path string) tea.Cmd {
+	return func() tea.Msg {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fileInfoMsg{Path: path}
+		}
+		msg := fileInfoMsg{Path: path, Size: info.Size()}
+		if info.Size() <= maxPageScanSize {
+			if data, err := os.ReadFile(path); err == nil {
+				// Pages packed into compressed object streams aren't visible
+				// here, in which case this finds none and Pages stays 0.
+				msg.Pages = len(pageObject.FindAllIndex(data, -1))
+			}
+		}
+		return msg
+	}
+}
+
+// String renders the info as e.g. "1.4 MB, 3 pages".
+func (f fileInfoMsg) String() string {
+	s := humanSize(f.Size)
+	switch {
+	case f.Pages == 1:
+		s += ", 1 page"
+	case f.Pages > 1:
+		s += fmt.Sprintf(", %d pages", f.Pages)
+	}
+	return s
+}
+
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
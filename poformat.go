@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"unicode"
+)
+
+// ----- PO Format -----
+
+// poFormat is the optional expected shape of a PO number (config key
+// "po_pattern", flag -po-pattern), checked while searching by PO number.
+type poFormat struct {
+	pattern string
+	re      *regexp.Regexp
+	ranges  []rune // pairs of lo, hi covering every rune the pattern can match
+	anyRune bool
+}
+
+// newPOFormat compiles pattern, anchored so it must match the whole query.
+// An empty pattern disables validation.
+func newPOFormat(pattern string) (*poFormat, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	tree, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("bad PO pattern %q: %v", pattern, err)
+	}
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("bad PO pattern %q: %v", pattern, err)
+	}
+	f := &poFormat{pattern: pattern, re: re}
+	f.collect(tree)
+	return f, nil
+}
+
+// collect gathers the runes a pattern's literals and classes can match.
+func (f *poFormat) collect(re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			f.ranges = append(f.ranges, r, r)
+			if re.Flags&syntax.FoldCase != 0 {
+				for c := unicode.SimpleFold(r); c != r; c = unicode.SimpleFold(c) {
+					f.ranges = append(f.ranges, c, c)
+				}
+			}
+		}
+	case syntax.OpCharClass:
+		f.ranges = append(f.ranges, re.Rune...)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		f.anyRune = true
+	}
+	for _, sub := range re.Sub {
+		f.collect(sub)
+	}
+}
+
+// allows reports whether r can appear anywhere in a matching PO number, so
+// keys that never could are rejected as they're typed.
+func (f *poFormat) allows(r rune) bool {
+	if f.anyRune {
+		return true
+	}
+	for i := 0; i+1 < len(f.ranges); i += 2 {
+		if f.ranges[i] <= r && r <= f.ranges[i+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsAll reports whether every rune of s passes allows.
+func (f *poFormat) allowsAll(s string) bool {
+	for _, r := range s {
+		if !f.allows(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validate is the search input's Validate hook: incomplete or malformed
+// numbers are flagged, but an empty input isn't.
+func (f *poFormat) validate(s string) error {
+	if s == "" || f.re.MatchString(s) {
+		return nil
+	}
+	return fmt.Errorf("expected a PO number like %s", f.pattern)
+}
+
+// applySearchValidation turns format checking on for PO-number searches and
+// off for the other fields, which can hold anything.
+func (m *model) applySearchValidation() {
+	if m.poFormat == nil || m.searchField != fieldPO {
+		m.searchInput.Validate = nil
+		m.searchInput.Err = nil
+		return
+	}
+	m.searchInput.Validate = m.poFormat.validate
+	m.searchInput.Err = m.poFormat.validate(m.searchInput.Value())
+}
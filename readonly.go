@@ -0,0 +1,28 @@
+package main
+
+import "github.com/charmbracelet/bubbles/key"
+
+// ----- Read-only Mode -----
+
+// With -readonly, SQLite is opened with mode=ro and the schema is left as it
+// is, and the actions that write are refused up front: saving parses,
+// deleting, pinning and exporting. Parsing, searching and opening PDFs work
+// as usual.
+
+const readOnlyNotice = "Read-only mode."
+
+// markReadOnly labels the refused keys in the help.
+func (k *keyMap) markReadOnly() {
+	for _, b := range []*key.Binding{&k.Export, &k.CSV, &k.Delete, &k.Pin, &k.Preview} {
+		b.SetHelp(b.Help().Key, b.Help().Desc+" (off: read-only)")
+	}
+}
+
+// refuseReadOnly reports whether a write must be refused, saying so in the
+// status.
+func (m *model) refuseReadOnly() bool {
+	if m.readonly {
+		m.status = readOnlyNotice
+	}
+	return m.readonly
+}
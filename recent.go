@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Recent Files -----
+
+const maxRecent = 10
+
+// recentMissing marks a recent file that has since been moved or deleted.
+const recentMissing = "missing"
+
+func recentFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "recent_files"), nil
+}
+
+// loadRecent reads recently parsed PDF paths, newest first.
+func loadRecent() []string {
+	path, err := recentFile()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var recent []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && len(recent) < maxRecent {
+			recent = append(recent, line)
+		}
+	}
+	return recent
+}
+
+// saveRecent writes the list in the background, like saveHistory.
+func saveRecent(recent []string) tea.Cmd {
+	data := strings.Join(recent, "\n") + "\n"
+	return func() tea.Msg {
+		path, err := recentFile()
+		if err != nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil
+		}
+		os.WriteFile(path, []byte(data), 0o644)
+		return nil
+	}
+}
+
+// addRecent puts path first, dropping any earlier copy, files that no longer
+// exist and the oldest entries beyond maxRecent.
+func addRecent(recent []string, path string) []string {
+	out := []string{path}
+	for _, p := range recent {
+		if p != path && fileExists(p) && len(out) < maxRecent {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// removeRecent drops path from the list.
+func removeRecent(recent []string, path string) []string {
+	var out []string
+	for _, p := range recent {
+		if p != path {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// recentRows lists each path with a status, so moved files stand out
+// instead of failing only once picked.
+func recentRows(recent []string) []table.Row {
+	rows := make([]table.Row, len(recent))
+	for i, p := range recent {
+		status := ""
+		if !fileExists(p) {
+			status = recentMissing
+		}
+		rows[i] = table.Row{p, status}
+	}
+	return rows
+}
+
+// recentColumns gives the path column whatever width the status leaves.
+func recentColumns(width int) []table.Column {
+	const statusWidth = len(recentMissing)
+	pathWidth := 50
+	if width > 0 {
+		pathWidth = max(width-2*cellPadding-statusWidth, minColumnWidth)
+	}
+	return []table.Column{
+		{Title: "Recent file", Width: pathWidth},
+		{Title: "", Width: statusWidth},
+	}
+}
+
+// showingRecent reports whether the upload tab is showing the recent files,
+// which it does until the first parse of the session.
+func (m model) showingRecent() bool {
+	return m.activeTab == tabUpload && m.output == "" && !m.loading() && !m.showError && !m.showBatch && len(m.recent) > 0
+}
+
+// pickRecent parses the selected recent file, or drops it from the list if
+// it has gone missing.
+func (m model) pickRecent() (tea.Model, tea.Cmd) {
+	row := m.recentTable.SelectedRow()
+	if row == nil {
+		return m, nil
+	}
+	path := row[0]
+	if !fileExists(path) {
+		m.recent = removeRecent(m.recent, path)
+		m.recentTable.SetRows(recentRows(m.recent))
+		if m.recentTable.Cursor() >= len(m.recent) {
+			m.recentTable.SetCursor(max(len(m.recent)-1, 0))
+		}
+		m.status = "File no longer exists; removed from recent files: " + path
+		return m, saveRecent(m.recent)
+	}
+	m.begin()
+	return m, tea.Batch(func() tea.Msg { return fileSelectedMsg(path) }, m.spinner.Tick)
+}
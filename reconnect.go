@@ -0,0 +1,109 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ----- Reconnect -----
+
+// A sync tool may swap warehouse.db for a fresh copy while the TUI runs.
+// Pooled SQLite connections keep the old file open, so reads would go stale
+// and writes would land in a file nobody sees. retryBusy checks the file
+// before each operation and retries once after reconnecting on the errors a
+// moved file gives.
+
+// watchedFile is the SQLite file the session opened, as it was then.
+type watchedFile struct {
+	path     string
+	info     os.FileInfo
+	readonly bool
+}
+
+// sessionFile is set by openDatabase; commands run concurrently, so it's
+// only touched under sessionMu.
+var (
+	sessionMu   sync.Mutex
+	sessionFile watchedFile
+)
+
+// poolIdleConns is database/sql's default, restored after a reconnect.
+const poolIdleConns = 2
+
+func watchFile(path string, readonly bool) watchedFile {
+	if strings.HasPrefix(path, ":") || strings.HasPrefix(path, "file:") {
+		return watchedFile{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return watchedFile{}
+	}
+	return watchedFile{path, info, readonly}
+}
+
+// replaced reports whether the path now names a different file. A file
+// that's missing for the moment, mid-swap, doesn't count.
+func (w watchedFile) replaced() bool {
+	if w.info == nil {
+		return false
+	}
+	info, err := os.Stat(w.path)
+	return err == nil && !os.SameFile(w.info, info)
+}
+
+// isStale spots the errors SQLite gives on a file that was moved or deleted
+// underneath an open connection.
+func isStale(err error) bool {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	if err == nil || sessionFile.info == nil {
+		return false
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "attempt to write a readonly database") {
+		return !sessionFile.readonly
+	}
+	return strings.Contains(msg, "disk I/O error") || strings.Contains(msg, "database disk image is malformed")
+}
+
+// reconnectIfReplaced is checked before each operation in retryBusy.
+func reconnectIfReplaced(db *sql.DB) {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	if sessionFile.replaced() {
+		reconnectLocked(db, "file replaced")
+	}
+}
+
+// reconnect drops the pooled connections so the next query opens the file
+// at the path afresh; the *sql.DB the commands hold stays valid. It reports
+// whether the new file answers.
+func reconnect(db *sql.DB, reason string) bool {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	return reconnectLocked(db, reason)
+}
+
+func reconnectLocked(db *sql.DB, reason string) bool {
+	debugLog.Printf("db reconnect: %s (%s)", sessionFile.path, reason)
+	if _, err := os.Stat(sessionFile.path); err != nil {
+		// Connecting now would create an empty database in its place.
+		debugLog.Printf("db reconnect error: %v", err)
+		return false
+	}
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(poolIdleConns)
+	if err := db.Ping(); err != nil {
+		debugLog.Printf("db reconnect error: %v", err)
+		return false
+	}
+	if !sessionFile.readonly {
+		if err := initSchema(db); err != nil {
+			debugLog.Printf("db reconnect error: %v", err)
+		}
+	}
+	sessionFile = watchFile(sessionFile.path, sessionFile.readonly)
+	return true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Retry -----
+
+type retryKind int
+
+const (
+	retryParse retryKind = iota
+	retrySave
+	retrySearch
+	retryList
+)
+
+// failedOp keeps the inputs of the last failed operation so R can send the
+// same command again, e.g. after a locked database or a busy Python env.
+type failedOp struct {
+	kind  retryKind
+	path  string        // parse, save
+	po    string        // save
+	order PurchaseOrder // save
+	query string        // search
+	field searchField
+	page  int // list
+}
+
+// fail records op as the one R will retry.
+func (m *model) fail(op failedOp) {
+	m.lastFailure = &op
+}
+
+// succeeded drops a stored failure once the same kind of operation works.
+func (m *model) succeeded(kind retryKind) {
+	if m.lastFailure != nil && m.lastFailure.kind == kind {
+		m.lastFailure = nil
+	}
+}
+
+func (m model) retry() (tea.Model, tea.Cmd) {
+	op := m.lastFailure
+	if op == nil {
+		m.status = "Nothing to retry."
+		return m, nil
+	}
+	switch op.kind {
+	case retryParse:
+		if m.cancelParse != nil {
+			m.status = "Busy. Wait for the current job or press esc to cancel."
+			return m, nil
+		}
+		m.activeTab = tabUpload
+		m.begin()
+		path := op.path
+		return m, tea.Batch(func() tea.Msg { return fileSelectedMsg(path) }, m.spinner.Tick)
+	case retrySave:
+		m.activeTab = tabUpload
+		m.status = "Saving PO " + op.po + "..."
+		return m, saveParseResult(m.db, op.po, op.path, op.order)
+	case retrySearch:
+		m.activeTab = tabSearch
+		m.searchField = op.field
+		m.applySearchValidation()
+		m.searchInput.SetValue(op.query)
+		m.lastQuery = op.query
+		m.status = "Searching database..."
+		m.begin()
+		return m, tea.Batch(searchDatabase(m.db, op.query, op.field), m.spinner.Tick)
+	default:
+		m.activeTab = tabList
+		m.status = "Loading purchase orders..."
+		cmd := m.loadList(op.page)
+		return m, cmd
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ----- Result Schema -----
+
+// resultSchema is the subset of JSON Schema the parser's output is checked
+// against: required top-level keys and each property's type. A result that
+// fails is still shown, marked as suspect.
+type resultSchema struct {
+	Required   []string                  `json:"required"`
+	Properties map[string]schemaProperty `json:"properties"`
+}
+
+// schemaProperty accepts "type" as one name or a list of names.
+type schemaProperty struct {
+	Type schemaTypes `json:"type"`
+}
+
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(b []byte) error {
+	var one string
+	if json.Unmarshal(b, &one) == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = many
+	return nil
+}
+
+// defaultSchema describes what parser_cli.py returns.
+var defaultSchema = &resultSchema{
+	Required: []string{"po_number"},
+	Properties: map[string]schemaProperty{
+		"po_number":      {schemaTypes{"string"}},
+		"vendor":         {schemaTypes{"string"}},
+		"invoice_number": {schemaTypes{"string"}},
+		"date":           {schemaTypes{"string"}},
+		"total":          {schemaTypes{"number"}},
+		"items":          {schemaTypes{"array"}},
+		"page":           {schemaTypes{"integer"}},
+		"_raw_text":      {schemaTypes{"string"}},
+	},
+}
+
+// schemaOff turns checking off (flag -schema, config key "schema").
+const schemaOff = "none"
+
+var schemaTypeNames = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true,
+	"array": true, "object": true, "null": true,
+}
+
+// loadSchema reads the schema file at path; "" means the built-in schema and
+// "none" means no checking.
+func loadSchema(path string) (*resultSchema, error) {
+	switch path {
+	case "":
+		return defaultSchema, nil
+	case schemaOff:
+		return nil, nil
+	}
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("schema error: %v", err)
+	}
+	var s resultSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("schema error in %s: %v", path, err)
+	}
+	for key, prop := range s.Properties {
+		for _, t := range prop.Type {
+			if !schemaTypeNames[t] {
+				return nil, fmt.Errorf("schema error in %s: unknown type %q for %s", path, t, key)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// check lists how raw, the parser's JSON, falls short of the schema. No
+// problems means the result looks complete.
+func (s *resultSchema) check(raw string) []string {
+	if s == nil {
+		return nil
+	}
+	var out map[string]interface{}
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&out); err != nil || out == nil {
+		return []string{"output is not a JSON object"}
+	}
+	var problems []string
+	for _, key := range s.Required {
+		if v, ok := out[key]; !ok {
+			problems = append(problems, "missing "+key)
+		} else if v == "" {
+			problems = append(problems, key+" is empty")
+		}
+	}
+	keys := make([]string, 0, len(s.Properties))
+	for k := range s.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		v, ok := out[key]
+		want := s.Properties[key].Type
+		if !ok || len(want) == 0 {
+			continue
+		}
+		if got := jsonType(v); !want.allows(got) {
+			problems = append(problems, fmt.Sprintf("%s is %s, expected %s", key, got, strings.Join(want, " or ")))
+		}
+	}
+	return problems
+}
+
+func (t schemaTypes) allows(got string) bool {
+	for _, want := range t {
+		if want == got || (want == "number" && got == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names v's JSON Schema type; v comes from a UseNumber decode.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if !bytes.ContainsAny([]byte(v), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
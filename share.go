@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Share -----
+
+// parseCommandLine is the "pdf-parser parse" invocation that repeats a parse
+// of path outside the TUI: the same parser, extraction options and timeout,
+// with every path absolute so it runs from any directory. A password is
+// never included.
+func parseCommandLine(opts parserOptions, timeout time.Duration, path string) string {
+	exe := "pdf-parser"
+	if p, err := os.Executable(); err == nil {
+		exe = p
+	}
+	var env []string
+	args := []string{exe, "parse"}
+	if opts.Backend == "exec" {
+		args = append(args, "-parser", "exec", "-parser-cmd", commandPath(opts.Command))
+	} else {
+		if opts.Python != defaultPython {
+			env = append(env, "PDFPARSER_PYTHON="+shellQuote(opts.Python))
+		}
+		args = append(args, "-script", absPath(opts.Script))
+	}
+	if opts.OCR != "" && opts.OCR != defaultOCR {
+		args = append(args, "-ocr", opts.OCR)
+	}
+	if opts.Template != "" {
+		args = append(args, "-template", opts.Template)
+	}
+	if opts.JSONL {
+		args = append(args, "-jsonl")
+	}
+	if timeout != defaultParseTimeout {
+		args = append(args, "-timeout", timeout.String())
+	}
+	args = append(args, absPath(path))
+	for i, a := range args {
+		args[i] = shellQuote(a)
+	}
+	return strings.Join(append(env, args...), " ")
+}
+
+// commandPath makes a relative command path absolute but leaves a bare
+// name, which exec finds on $PATH, as it is.
+func commandPath(command string) string {
+	if !strings.ContainsRune(command, '/') && !strings.ContainsRune(command, filepath.Separator) {
+		return command
+	}
+	return absPath(command)
+}
+
+// shellQuote single-quotes s for a POSIX shell unless it's plainly safe.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,+@") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// copyParseCommand copies the command for the PDF the tab is showing.
+func (m *model) copyParseCommand() tea.Cmd {
+	path := m.folderPath()
+	if m.activeTab == tabUpload {
+		path = firstNonEmpty(m.lastParsed, m.uploadPath)
+	}
+	if path == "" {
+		m.status = "No PDF yet. Parse or search for one first."
+		return nil
+	}
+	notice := "Copied the parse command for " + filepath.Base(path) + "."
+	if m.password != "" {
+		notice += " Set PDFPARSER_PASSWORD to run it."
+	}
+	return copyToClipboard(parseCommandLine(m.parser, m.parseTimeout, path), notice)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	cwd, _ := os.Getwd()
+	tests := []struct {
+		command, want string
+	}{
+		{"pdftotext-wrapper", " -parser-cmd pdftotext-wrapper "},
+		{"./bin/wrapper", " -parser-cmd " + filepath.Join(cwd, "bin/wrapper") + " "},
+		{"/opt/wrapper", " -parser-cmd /opt/wrapper "},
+	}
+	for _, tt := range tests {
+		got := parseCommandLine(parserOptions{Backend: "exec", Command: tt.command}, defaultParseTimeout, "/tmp/it's.pdf")
+		if !strings.Contains(got, tt.want) || !strings.HasSuffix(got, ` '/tmp/it'\''s.pdf'`) {
+			t.Errorf("%s: got %s", tt.command, got)
+		}
+	}
+}
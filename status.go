@@ -0,0 +1,52 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Status Line -----
+
+// noticeTTL is how long a confirmation such as "Copied to clipboard." stays.
+const noticeTTL = 2 * time.Second
+
+// notice is an ephemeral status message layered over m.status. base records
+// m.status when it was posted: once m.status changes, a permanent message
+// has replaced whatever the notice was covering and the notice is dropped.
+type notice struct {
+	text    string
+	base    string
+	expires time.Time
+}
+
+type expireNoticesMsg struct{}
+
+// notify shows text for ttl, then falls back to the previous notice still
+// alive or to m.status. Errors and other lasting messages should be
+// assigned to m.status directly instead.
+func (m *model) notify(text string, ttl time.Duration) tea.Cmd {
+	m.notices = append(m.notices, notice{text, m.status, time.Now().Add(ttl)})
+	return tea.Tick(ttl, func(time.Time) tea.Msg { return expireNoticesMsg{} })
+}
+
+// expireNotices drops notices that have timed out or been superseded.
+func (m *model) expireNotices(now time.Time) {
+	live := m.notices[:0]
+	for _, n := range m.notices {
+		if now.Before(n.expires) && n.base == m.status {
+			live = append(live, n)
+		}
+	}
+	m.notices = live
+}
+
+// statusLine is the text to show: the newest live notice, else m.status.
+func (m model) statusLine() string {
+	for i := len(m.notices) - 1; i >= 0; i-- {
+		if m.notices[i].base == m.status {
+			return m.notices[i].text
+		}
+	}
+	return m.status
+}
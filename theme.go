@@ -0,0 +1,193 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
+)
+
+// ----- Themes -----
+
+type theme struct {
+	Name       string
+	Background lipgloss.Color
+	Text       lipgloss.Color
+	Accent     lipgloss.Color
+	Muted      lipgloss.Color // content dimmed behind the loading overlay
+}
+
+var themes = []theme{
+	{Name: "matrix", Background: "#000000", Text: "#00ff00", Accent: "#00ff00", Muted: "#005f00"},
+	{Name: "solarized", Background: "#002b36", Text: "#839496", Accent: "#b58900", Muted: "#586e75"},
+	{Name: "mono", Background: "#000000", Text: "#d0d0d0", Accent: "#ffffff", Muted: "#5f5f5f"},
+}
+
+// spinners maps the -spinner names to bubbles' frame sets. The theme colors
+// whichever is picked.
+var spinners = map[string]spinner.Spinner{
+	"line":      spinner.Line,
+	"dot":       spinner.Dot,
+	"minidot":   spinner.MiniDot,
+	"jump":      spinner.Jump,
+	"pulse":     spinner.Pulse,
+	"points":    spinner.Points,
+	"globe":     spinner.Globe,
+	"moon":      spinner.Moon,
+	"monkey":    spinner.Monkey,
+	"meter":     spinner.Meter,
+	"hamburger": spinner.Hamburger,
+	"ellipsis":  spinner.Ellipsis,
+}
+
+const defaultSpinner = "line"
+
+// spinnerNames lists the choices for help and error text.
+func spinnerNames() string {
+	names := make([]string, 0, len(spinners))
+	for name := range spinners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// styles are rebuilt from the active theme so switching re-renders everything.
+type styles struct {
+	Base       lipgloss.Style
+	Box        lipgloss.Style
+	Title      lipgloss.Style
+	CenterText lipgloss.Style
+	Highlight  lipgloss.Style // search matches in result tables
+	Table      table.Styles
+	Dim        lipgloss.Style // what's behind the loading overlay
+	Overlay    lipgloss.Style
+}
+
+func newStyles(t theme) styles {
+	base := lipgloss.NewStyle().Background(t.Background).Foreground(t.Text)
+	return styles{
+		Base:       base,
+		Box:        base.Border(borderStyle, true).BorderForeground(t.Accent).Padding(1, 2),
+		Title:      base.Bold(true).Foreground(t.Accent).Align(lipgloss.Center),
+		CenterText: base.Align(lipgloss.Center),
+		Highlight:  lipgloss.NewStyle().Bold(true).Underline(true).Foreground(t.Background).Background(t.Accent),
+		Table:      tableStyles(lipgloss.NewStyle().Bold(true).Foreground(t.Accent), lipgloss.NewStyle().Bold(true).Foreground(t.Background).Background(t.Accent)),
+		Dim:        base.Foreground(t.Muted),
+		Overlay:    base.Border(borderStyle, true).BorderForeground(t.Accent).Padding(0, 2),
+	}
+}
+
+// tableStyles keep table.DefaultStyles' layout, a one-line header and one
+// cell of padding each side, which highlightTable and the mouse code rely on.
+// The selected row is inverted rather than recolored so it stands out on a
+// black background. Highlight is underlined for the same reason: a match in
+// the selected row would otherwise vanish into it.
+func tableStyles(header, selected lipgloss.Style) table.Styles {
+	return table.Styles{
+		Header:   header.Padding(0, 1),
+		Cell:     lipgloss.NewStyle().Padding(0, 1),
+		Selected: selected,
+	}
+}
+
+// plainStyles drop all colors and use an ASCII border, for NO_COLOR and
+// terminals that can't render the themed look.
+func plainStyles() styles {
+	base := lipgloss.NewStyle()
+	return styles{
+		Base:       base,
+		Box:        base.Border(lipgloss.ASCIIBorder(), true).Padding(1, 2),
+		Title:      base.Bold(true).Align(lipgloss.Center),
+		CenterText: base.Align(lipgloss.Center),
+		Highlight:  base.Reverse(true).Underline(true),
+		Table:      tableStyles(base.Bold(true), base.Bold(true).Reverse(true)),
+		Dim:        base.Faint(true),
+		Overlay:    base.Border(lipgloss.ASCIIBorder(), true).Padding(0, 2),
+	}
+}
+
+// colorDisabled honors NO_COLOR (https://no-color.org) and skips color when
+// stdout isn't a terminal.
+func colorDisabled() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return true
+	}
+	fd := os.Stdout.Fd()
+	return !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd)
+}
+
+// themeIndex finds a preset by name, falling back to the first (matrix).
+func themeIndex(name string) int {
+	for i, t := range themes {
+		if strings.EqualFold(t.Name, name) {
+			return i
+		}
+	}
+	return 0
+}
+
+func themeFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "theme"), nil
+}
+
+// loadSavedTheme returns the theme name chosen in a previous session, if any.
+func loadSavedTheme() string {
+	path, err := themeFile()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func saveTheme(name string) tea.Cmd {
+	return func() tea.Msg {
+		path, err := themeFile()
+		if err != nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil
+		}
+		os.WriteFile(path, []byte(name+"\n"), 0o644)
+		return nil
+	}
+}
+
+// applyTheme switches the palette and restyles the widgets that cache colors.
+func (m *model) applyTheme(i int) {
+	m.themeIdx = i
+	if m.noColor {
+		m.styles = plainStyles()
+		m.spinner.Style = m.styles.Base
+		m.progress.FullColor = ""
+		m.progress.EmptyColor = ""
+		m.restyleTables()
+		return
+	}
+	t := themes[i]
+	m.styles = newStyles(t)
+	m.spinner.Style = m.styles.Base.Foreground(t.Accent)
+	m.progress.FullColor = string(t.Accent)
+	m.restyleTables()
+}
+
+func (m *model) restyleTables() {
+	for _, t := range []*table.Model{&m.table, &m.searchTable, &m.itemsTable, &m.recentTable, &m.listTable, &m.diffTable, &m.batchTable} {
+		t.SetStyles(m.styles.Table)
+	}
+}
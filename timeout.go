@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ----- Parse Timeout -----
+
+const (
+	// timeoutStep is how far + and - move the parse timeout.
+	timeoutStep     = 5 * time.Second
+	minParseTimeout = timeoutStep
+)
+
+type timeoutSavedMsg struct{ Err error }
+
+// adjustTimeout moves the parse timeout by one step and saves it. A running
+// parse keeps the deadline it started with.
+func (m *model) adjustTimeout(longer bool) tea.Cmd {
+	if longer {
+		m.parseTimeout += timeoutStep
+	} else {
+		m.parseTimeout -= timeoutStep
+	}
+	m.parseTimeout = max(m.parseTimeout, minParseTimeout)
+	text := "Parse timeout: " + m.parseTimeout.String()
+	if m.cancelParse != nil {
+		text += " (from the next parse)"
+	}
+	return tea.Batch(m.notify(text, noticeTTL), saveTimeout(m.parseTimeout))
+}
+
+// timeLeft is the running parse's countdown, such as " (25s left)".
+func (m model) timeLeft() string {
+	if m.parseDeadline.IsZero() {
+		return ""
+	}
+	left := max(time.Until(m.parseDeadline), 0).Round(time.Second)
+	return fmt.Sprintf(" (%s left)", left)
+}
+
+// saveTimeout writes timeout to config.json so the next session starts with
+// it. The file's other keys are kept as they are.
+func saveTimeout(timeout time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		path, err := configFile()
+		if err != nil {
+			return timeoutSavedMsg{fmt.Errorf("config error: %v", err)}
+		}
+		keys := map[string]json.RawMessage{}
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = json.Unmarshal(data, &keys)
+		} else if errors.Is(err, os.ErrNotExist) {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err != nil {
+			return timeoutSavedMsg{fmt.Errorf("config error: %v", err)}
+		}
+		keys["timeout"], _ = json.Marshal(timeout.String())
+		data, _ = json.MarshalIndent(keys, "", "  ")
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			return timeoutSavedMsg{fmt.Errorf("config error: %v", err)}
+		}
+		return timeoutSavedMsg{nil}
+	}
+}
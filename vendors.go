@@ -0,0 +1,104 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ----- Vendor Suggestions -----
+
+// maxVendorSuggestions caps the dropdown under the search input.
+const maxVendorSuggestions = 6
+
+// vendorsMsg carries every distinct vendor, or none when the database has no
+// vendor column.
+type vendorsMsg struct {
+	Vendors []string
+	Err     error
+}
+
+// loadVendors reads the vendor list once, the first time a vendor search
+// is picked; the model keeps it for the rest of the session.
+func loadVendors(db *sql.DB) tea.Cmd {
+	return func() tea.Msg {
+		has, err := hasColumn(db, "purchase_orders", "vendor")
+		if err != nil || !has {
+			return vendorsMsg{nil, err}
+		}
+		var vendors []string
+		err = retryBusy(db, func() error {
+			vendors = nil
+			rows, err := db.Query("SELECT DISTINCT vendor FROM purchase_orders WHERE vendor IS NOT NULL AND vendor != '' ORDER BY vendor")
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var v string
+				if err := rows.Scan(&v); err != nil {
+					return err
+				}
+				vendors = append(vendors, v)
+			}
+			return rows.Err()
+		})
+		if isBusy(err) {
+			return vendorsMsg{nil, errDatabaseBusy}
+		} else if err != nil {
+			return vendorsMsg{nil, fmt.Errorf("DB query error: %v", err)}
+		}
+		return vendorsMsg{vendors, nil}
+	}
+}
+
+// vendorSuggestions are the known vendors containing what's typed, shown
+// while a vendor search is being entered and hidden once it has run.
+func (m model) vendorSuggestions() []string {
+	query := strings.ToLower(strings.TrimSpace(m.searchInput.Value()))
+	if m.activeTab != tabSearch || !m.searchInput.Focused() || m.searchField != fieldVendor || query == "" || m.searchInput.Value() == m.lastQuery {
+		return nil
+	}
+	var out []string
+	for _, v := range m.vendors {
+		if strings.Contains(strings.ToLower(v), query) {
+			out = append(out, v)
+			if len(out) == maxVendorSuggestions {
+				break
+			}
+		}
+	}
+	return out
+}
+
+// pickVendor moves the dropdown highlight; -1 leaves the dropdown, so Enter
+// searches the text as typed.
+func (m *model) pickVendor(down bool) {
+	n := len(m.vendorSuggestions())
+	if down {
+		m.vendorPick = min(m.vendorPick+1, n-1)
+	} else {
+		m.vendorPick = max(m.vendorPick-1, -1)
+	}
+}
+
+// vendorDropdown renders the suggestions, highlighting the picked one.
+func (m model) vendorDropdown() string {
+	suggestions := m.vendorSuggestions()
+	if len(suggestions) == 0 {
+		return ""
+	}
+	lines := make([]string, len(suggestions))
+	for i, v := range suggestions {
+		if i == m.vendorPick {
+			lines[i] = m.styles.Highlight.Render("› " + v)
+		} else {
+			lines[i] = "  " + v
+		}
+	}
+	block := strings.Join(lines, "\n") + "\n\n↑/↓ pick a vendor, Enter to search."
+	return "\n" + m.styles.CenterText.Width(m.width).Render(m.styles.Base.Width(lipgloss.Width(block)).Render(block))
+}
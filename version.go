@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ----- Version -----
+
+// version is set at build time:
+//
+//	go build -ldflags "-X main.version=1.2.0"
+var version = "dev"
+
+// aboutText is the version and setup to include in bug reports, shown by
+// -version and the v overlay.
+func aboutText(dbPath string, opts parserOptions) string {
+	lines := []string{
+		"pdf-parser " + version,
+		fmt.Sprintf("Go:       %s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH),
+		"",
+		"Database: " + dbPath,
+		"Parser:   " + opts.Backend,
+	}
+	if opts.Backend == "exec" {
+		lines = append(lines, "Command:  "+opts.Command)
+	} else {
+		lines = append(lines, "Script:   "+opts.Script, "Python:   "+resolvedCommand(opts.Python))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// resolvedCommand shows which file a bare command name like python3 runs.
+func resolvedCommand(name string) string {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return name + " (not found)"
+	}
+	if path == name {
+		return name
+	}
+	return name + " (" + path + ")"
+}